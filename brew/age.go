@@ -0,0 +1,104 @@
+package brew
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/thepwagner/action-update/updater"
+)
+
+// versionDates records when versions were published, for sources that list it.
+type versionDates map[string]time.Time
+
+func (d versionDates) add(version string, published time.Time) {
+	if d != nil && !published.IsZero() {
+		d[version] = published
+	}
+}
+
+// parseMinAge parses an age in days like "3" or "3d", or a duration like "12h".
+func parseMinAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if days, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil {
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q, expected days like 3d or a duration like 12h", s)
+	}
+	return d, nil
+}
+
+// heldBack returns true if a version was published too recently to be proposed. Versions published at an unknown
+// date are held back, as their age can't be verified.
+func (u Updater) heldBack(ctx context.Context, dep updater.Dependency, version string, dates versionDates) bool {
+	if u.minAge <= 0 {
+		return false
+	}
+	log := logrus.WithFields(logrus.Fields{
+		"path":    dep.Path,
+		"version": version,
+	})
+	published := u.published(ctx, dep, version, dates)
+	if published.IsZero() {
+		log.Warn("release date unknown, version held back")
+		return true
+	}
+	if age := time.Since(published); age < u.minAge {
+		log.WithFields(logrus.Fields{
+			"published": published,
+			"min_age":   u.minAge,
+		}).Info("version held back until it reaches the minimum age")
+		return true
+	}
+	return false
+}
+
+// published returns when a version was published: as listed by its source, or the Last-Modified header of its
+// artifact. The zero time is returned if neither is known.
+func (u Updater) published(ctx context.Context, dep updater.Dependency, version string, dates versionDates) time.Time {
+	if t, ok := dates[version]; ok {
+		return t
+	}
+
+	artifactURL := updatedTemplateURL(dep.Path, updater.Update{Path: dep.Path, Previous: dep.Version, Next: version})
+	log := logrus.WithField("url", artifactURL)
+	req, err := http.NewRequest("HEAD", artifactURL, nil)
+	if err != nil {
+		log.WithError(err).Warn("error checking release date")
+		return time.Time{}
+	}
+	res, err := u.client.Do(req.WithContext(ctx))
+	if err != nil {
+		log.WithError(err).Warn("error checking release date")
+		return time.Time{}
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return time.Time{}
+	}
+	t, err := http.ParseTime(res.Header.Get("Last-Modified"))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// withoutVersion removes a version from a list.
+func withoutVersion(versions []string, version string) []string {
+	ret := make([]string, 0, len(versions))
+	for _, v := range versions {
+		if v != version {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
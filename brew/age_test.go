@@ -0,0 +1,134 @@
+package brew
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thepwagner/action-update/updater"
+)
+
+func TestParseMinAge(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":    0,
+		"3":   72 * time.Hour,
+		"3d":  72 * time.Hour,
+		"12h": 12 * time.Hour,
+	}
+	for s, expected := range cases {
+		t.Run(s, func(t *testing.T) {
+			age, err := parseMinAge(s)
+			require.NoError(t, err)
+			assert.Equal(t, expected, age)
+		})
+	}
+	_, err := parseMinAge("a week")
+	assert.Error(t, err)
+}
+
+func TestEnvironment_NewUpdater_MinAge(t *testing.T) {
+	u := (&Environment{MinAge: "3d"}).NewUpdater(t.TempDir())
+	require.IsType(t, &Updater{}, u)
+	assert.Equal(t, 72*time.Hour, u.(*Updater).minAge)
+
+	u = (&Environment{MinAge: "a week"}).NewUpdater(t.TempDir())
+	_, err := u.Dependencies(context.Background())
+	assert.Error(t, err)
+	_, err = u.Check(context.Background(), updater.Dependency{}, nil)
+	assert.Error(t, err)
+	assert.Error(t, u.ApplyUpdate(context.Background(), updater.Update{}))
+}
+
+func TestUpdater_Check_MinAge(t *testing.T) {
+	gh := newFakeGitHub(t)
+	now := time.Now()
+	gh.json("/api/repos/foo/foo/releases", []*github.RepositoryRelease{
+		{TagName: github.String("v1.2.0"), PublishedAt: &github.Timestamp{Time: now.Add(-time.Hour)}},
+		{TagName: github.String("v1.1.0"), PublishedAt: &github.Timestamp{Time: now.Add(-10 * 24 * time.Hour)}},
+		{TagName: github.String("v1.0.0"), PublishedAt: &github.Timestamp{Time: now.Add(-100 * 24 * time.Hour)}},
+	})
+
+	cases := map[string]struct {
+		minAge time.Duration
+		next   string
+	}{
+		"disabled":  {next: "1.2.0"},
+		"held back": {minAge: 3 * 24 * time.Hour, next: "1.1.0"},
+		"all young": {minAge: 30 * 24 * time.Hour},
+	}
+	for label, tc := range cases {
+		t.Run(label, func(t *testing.T) {
+			f := newFormulaFixture(t, "class Foo < Formula\n  url \"https://github.com/foo/foo/releases/download/v1.0.0/foo.tar.gz\"\nend\n", WithMinAge(tc.minAge))
+			f.ghRepos = gh.client.Repositories
+			update := f.check()
+			if tc.next == "" {
+				assert.Nil(t, update)
+				return
+			}
+			require.NotNil(t, update)
+			assert.Equal(t, tc.next, update.Next)
+		})
+	}
+}
+
+func TestUpdater_Check_MinAgeListing(t *testing.T) {
+	now := time.Now().UTC()
+	srv := newFakeServer(t, map[string]string{"/dist/foo": fmt.Sprintf(`<html><body><pre>
+<a href="foo-1.0.0/">foo-1.0.0/</a>      %s    -
+<a href="foo-1.1.0/">foo-1.1.0/</a>      %s    -
+</pre><table>
+<tr><td><a href="foo-1.2.0/">foo-1.2.0/</a></td><td align="right">%s  </td><td>-</td></tr>
+</table></body></html>`,
+		now.Add(-100*24*time.Hour).Format("2006-01-02 15:04"),
+		now.Add(-10*24*time.Hour).Format("02-Jan-2006 15:04"),
+		now.Add(-time.Hour).Format("2006-01-02 15:04")),
+	})
+
+	u := NewUpdater(t.TempDir(), WithMinAge(3*24*time.Hour))
+	update, err := u.Check(context.Background(), updater.Dependency{Path: srv.URL + "/dist/foo/foo-1.0.0/foo-1.0.0.tar.gz", Version: "1.0.0"}, nil)
+	require.NoError(t, err)
+	require.NotNil(t, update)
+	assert.Equal(t, "1.1.0", update.Next)
+}
+
+func TestUpdater_Check_MinAgeLastModified(t *testing.T) {
+	now := time.Now().UTC()
+	srv := newFakeServer(t, map[string]string{
+		"/dist/foo": `<a href="foo-1.0.0/">foo-1.0.0/</a> <a href="foo-1.1.0/">foo-1.1.0/</a> <a href="foo-1.2.0/">foo-1.2.0/</a> <a href="foo-1.3.0/">foo-1.3.0/</a>`,
+		// 1.3.0 has no Last-Modified header, its age is unknown:
+		"/dist/foo/foo-1.3.0/foo-1.3.0.tar.gz": "",
+	})
+	for version, age := range map[string]time.Duration{"1.1.0": 10 * 24 * time.Hour, "1.2.0": time.Hour} {
+		lastModified := now.Add(-age).Format(http.TimeFormat)
+		srv.mux.HandleFunc(fmt.Sprintf("/dist/foo/foo-%s/foo-%s.tar.gz", version, version), func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Last-Modified", lastModified)
+		})
+	}
+
+	u := NewUpdater(t.TempDir(), WithMinAge(3*24*time.Hour))
+	update, err := u.Check(context.Background(), updater.Dependency{Path: srv.URL + "/dist/foo/foo-1.0.0/foo-1.0.0.tar.gz", Version: "1.0.0"}, nil)
+	require.NoError(t, err)
+	require.NotNil(t, update)
+	assert.Equal(t, "1.1.0", update.Next)
+}
+
+func TestUpdater_Check_MinAgeUnreachable(t *testing.T) {
+	srv := newFakeServer(t, map[string]string{
+		"/dist/foo": `<a href="foo-1.0.0/">foo-1.0.0/</a> <a href="foo-1.1.0/">foo-1.1.0/</a>`,
+	})
+	srv.mux.HandleFunc("/dist/foo/foo-1.1.0/foo-1.1.0.tar.gz", func(w http.ResponseWriter, _ *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		_ = conn.Close()
+	})
+
+	u := NewUpdater(t.TempDir(), WithMinAge(3*24*time.Hour))
+	update, err := u.Check(context.Background(), updater.Dependency{Path: srv.URL + "/dist/foo/foo-1.0.0/foo-1.0.0.tar.gz", Version: "1.0.0"}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, update)
+}
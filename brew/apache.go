@@ -12,33 +12,37 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/sirupsen/logrus"
 	"github.com/thepwagner/action-update/updater"
-	"golang.org/x/mod/semver"
+	"golang.org/x/net/html"
 )
 
-func checkApacheRelease(ctx context.Context, client *http.Client, dep updater.Dependency) (*updater.Update, error) {
-	candidates, err := listApacheVersions(ctx, client, dep)
+// apacheSource discovers versions from HTML directory listings, like those of Apache mirrors. It is the fallback for
+// any URL that includes its version.
+type apacheSource struct{ u *Updater }
+
+func (s apacheSource) Match(dep updater.Dependency) bool {
+	_, _, err := getListing(dep)
+	return err == nil
+}
+
+func (s apacheSource) ListVersions(ctx context.Context, q VersionQuery) ([]SourceVersion, error) {
+	dates := versionDates{}
+	versions, err := listApacheVersions(ctx, s.u.client, q.Dependency, q.Scheme, dates)
 	if err != nil {
 		return nil, err
 	}
+	return sourceVersions(versions, dates), nil
+}
 
-	depVersion := semverIsh(dep.Version)
-	for _, version := range candidates {
-		if semver.Compare(depVersion, semverIsh(version)) < 0 {
-			return &updater.Update{
-				Path:     dep.Path,
-				Previous: dep.Version,
-				Next:     version,
-			}, nil
-		}
-	}
-	return nil, nil
+func (s apacheSource) ResolveHash(ctx context.Context, update updater.Update, oldHash string) (string, error) {
+	return updatedApacheHash(ctx, s.u.client, update, oldHash, s.u.gpg)
 }
 
-func listApacheVersions(ctx context.Context, client *http.Client, dep updater.Dependency) ([]string, error) {
+func listApacheVersions(ctx context.Context, client *http.Client, dep updater.Dependency, scheme VersionScheme, dates versionDates) ([]string, error) {
 	// Split the URL on the last component that includes the version:
 	listingURL, nextPath, err := getListing(dep)
 	if err != nil {
@@ -62,28 +66,51 @@ func listApacheVersions(ctx context.Context, client *http.Client, dep updater.De
 	if err != nil {
 		return nil, err
 	}
-	filter := regexp.MustCompile(semverRe.ReplaceAllString(nextPath, `(\d+\.\d+\.\d+)`) + "/?")
+	version := csvFirst(dep.Version)
+	i := strings.Index(nextPath, version)
+	filter := regexp.MustCompile(regexp.QuoteMeta(nextPath[:i]) + "(" + scheme.Pattern() + ")" + regexp.QuoteMeta(nextPath[i+len(version):]) + "/?")
 
 	var ret []string
 	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
 		match := filter.FindStringSubmatch(s.Text())
 		if len(match) > 0 {
 			ret = append(ret, match[1])
+			dates.add(match[1], listingDate(s))
 		}
 	})
-	semverSort(ret)
 	return ret, nil
 }
 
+// listingDateRe matches the Last-Modified column of directory listings, e.g. "2014-11-18 11:36" or "18-Nov-2014 11:36".
+var listingDateRe = regexp.MustCompile(`\d{4}-\d{2}-\d{2} \d{2}:\d{2}|\d{2}-[A-Z][a-z]{2}-\d{4} \d{2}:\d{2}`)
+
+// listingDate returns the Last-Modified date beside a link in a directory listing, or the zero time if there isn't one.
+// Listings are either table rows, or preformatted text following the link.
+func listingDate(link *goquery.Selection) time.Time {
+	text := link.Closest("tr").Text()
+	if text == "" {
+		for n := link.Nodes[0].NextSibling; n != nil && n.Type == html.TextNode; n = n.NextSibling {
+			text += n.Data
+		}
+	}
+	m := listingDateRe.FindString(text)
+	for _, layout := range []string{"2006-01-02 15:04", "02-Jan-2006 15:04"} {
+		if t, err := time.Parse(layout, m); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
 func getListing(dep updater.Dependency) (string, string, error) {
-	parsed, err := url.Parse(versionTemplate.ReplaceAllString(dep.Path, dep.Version))
+	parsed, err := url.Parse(expandVersion(dep.Path, dep.Version))
 	if err != nil {
 		return "", "", err
 	}
 	paths := strings.Split(parsed.Path, "/")
 
 	for i, pathPart := range paths {
-		if strings.Contains(pathPart, dep.Version) {
+		if strings.Contains(pathPart, csvFirst(dep.Version)) {
 			parsed.Path = strings.Join(paths[:i], "/")
 			return parsed.String(), pathPart, nil
 		}
@@ -92,7 +119,8 @@ func getListing(dep updater.Dependency) (string, string, error) {
 }
 
 func updatedApacheHash(ctx context.Context, client *http.Client, update updater.Update, oldHash string, gpg bool) (string, error) {
-	oldURL := versionTemplate.ReplaceAllString(update.Path, update.Previous)
+	oldURL := expandVersion(update.Path, update.Previous)
+	newURL := updatedTemplateURL(update.Path, update)
 	if ok, err := isHashAsset(ctx, client, oldURL, oldHash); err != nil {
 		return "", err
 	} else if !ok {
@@ -102,7 +130,7 @@ func updatedApacheHash(ctx context.Context, client *http.Client, update updater.
 	var signature string
 	if gpg {
 		var err error
-		signature, err = getUpdatedSignature(ctx, client, update, oldURL)
+		signature, err = getUpdatedSignature(ctx, client, newURL)
 		if err != nil {
 			logrus.WithError(err).Warn("error fetching updated signature, ignoring...")
 		} else if signature == "" {
@@ -110,13 +138,13 @@ func updatedApacheHash(ctx context.Context, client *http.Client, update updater.
 		}
 	}
 
-	res, err := getUpdatedAsset(ctx, client, oldURL, update)
+	res, err := getAsset(ctx, client, newURL)
 	if err != nil {
 		return "", err
 	}
 	defer res.Body.Close()
 
-	updatedFn := filepath.Base(updatedURL(oldURL, update))
+	updatedFn := filepath.Base(newURL)
 	h, _ := hasher(oldHash)
 	var assetOut io.Writer
 	var sigDir string
@@ -157,8 +185,8 @@ func updatedApacheHash(ctx context.Context, client *http.Client, update updater.
 	return fmt.Sprintf("%x", sum), nil
 }
 
-func getUpdatedSignature(ctx context.Context, client *http.Client, update updater.Update, oldURL string) (string, error) {
-	signatureRes, err := getUpdatedAsset(ctx, client, fmt.Sprintf("%s.asc", oldURL), update)
+func getUpdatedSignature(ctx context.Context, client *http.Client, newURL string) (string, error) {
+	signatureRes, err := getAsset(ctx, client, fmt.Sprintf("%s.asc", newURL))
 	if err != nil {
 		return "", err
 	}
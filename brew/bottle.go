@@ -0,0 +1,41 @@
+package brew
+
+// bottleEdits handles the `bottle do` blocks of a formula whose version changed, as the bottles were built from the
+// previous version. Bottle blocks are removed, or if a placeholder is configured their hashes are replaced with it.
+func bottleEdits(f *Formula, scope *Block, placeholder string) []edit {
+	var edits []edit
+	for _, bottle := range scope.Children("bottle") {
+		if placeholder == "" {
+			edits = append(edits, lineEdit(f.Source, bottle.Start, bottle.End))
+			continue
+		}
+
+		for _, s := range bottle.Stanzas {
+			switch s.Method {
+			case "rebuild":
+				// The rebuild counter resets with each version:
+				edits = append(edits, lineEdit(f.Source, s.Start, s.End))
+			case "sha256":
+				for _, h := range bottleHashes(s) {
+					edits = append(edits, edit{Start: h.Start, End: h.End, Text: placeholder})
+				}
+			}
+		}
+	}
+	return edits
+}
+
+// bottleHashes returns the per-platform hashes of a bottle's sha256 stanza, in either syntax:
+//
+//	sha256 cellar: :any, arm64_monterey: "..."
+//	sha256 "..." => :mojave
+func bottleHashes(s *Stanza) []Literal {
+	var hashes []Literal
+	for key, lit := range s.Options {
+		if key == "cellar" || lit.Symbol {
+			continue
+		}
+		hashes = append(hashes, lit)
+	}
+	return hashes
+}
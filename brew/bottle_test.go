@@ -0,0 +1,64 @@
+package brew
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thepwagner/action-update/updater"
+)
+
+const bottleFormula = `class Foo < Formula
+  url "%[1]s/foo-1.0.0.tar.gz"
+  sha256 "%[2]s"
+
+  bottle do
+    root_url "https://example.com/bottles/foo-1.0.0"
+    rebuild 1
+    sha256 cellar: :any, arm64_monterey: "f70e1ae8df182b242ca004492cc0a664e2a8195e2e46f30546fe78e265d5eb87"
+    sha256 cellar: :any_skip_relocation, big_sur: "674b3ae41c399f1e8e44c271b0e6909babff9fcd2e04a2127d25e2407ea4dd33"
+  end
+
+  depends_on "bar"
+end
+`
+
+func TestUpdater_Update_Bottle(t *testing.T) {
+	srv := newFakeServer(t, map[string]string{
+		"/foo-1.0.0.tar.gz": "foo 1.0.0",
+		"/foo-1.1.0.tar.gz": "foo 1.1.0",
+	})
+	f := newFormulaFixture(t, fmt.Sprintf(bottleFormula, srv.URL, sha256Hex("foo 1.0.0")))
+
+	expected := fmt.Sprintf(`class Foo < Formula
+  url "%[1]s/foo-1.1.0.tar.gz"
+  sha256 "%[2]s"
+
+  depends_on "bar"
+end
+`, srv.URL, sha256Hex("foo 1.1.0"))
+	assert.Equal(t, expected, f.apply(&updater.Update{Path: srv.URL + "/foo-1.0.0.tar.gz", Previous: "1.0.0", Next: "1.1.0"}))
+}
+
+func TestUpdater_Update_BottlePlaceholder(t *testing.T) {
+	srv := newFakeServer(t, map[string]string{
+		"/foo-1.0.0.tar.gz": "foo 1.0.0",
+		"/foo-1.1.0.tar.gz": "foo 1.1.0",
+	})
+	f := newFormulaFixture(t, fmt.Sprintf(bottleFormula, srv.URL, sha256Hex("foo 1.0.0")), WithBottlePlaceholder("TODO"))
+
+	expected := fmt.Sprintf(`class Foo < Formula
+  url "%[1]s/foo-1.1.0.tar.gz"
+  sha256 "%[2]s"
+
+  bottle do
+    root_url "https://example.com/bottles/foo-1.0.0"
+    sha256 cellar: :any, arm64_monterey: "TODO"
+    sha256 cellar: :any_skip_relocation, big_sur: "TODO"
+  end
+
+  depends_on "bar"
+end
+`, srv.URL, sha256Hex("foo 1.1.0"))
+	assert.Equal(t, expected, f.apply(&updater.Update{Path: srv.URL + "/foo-1.0.0.tar.gz", Previous: "1.0.0", Next: "1.1.0"}))
+}
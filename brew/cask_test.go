@@ -0,0 +1,49 @@
+package brew
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thepwagner/action-update/updater"
+)
+
+func TestUpdater_Update_Cask(t *testing.T) {
+	srv := newFakeServer(t, map[string]string{
+		"/100/Foo-1.2.3.dmg": "foo 1.2.3",
+		"/120/Foo-1.3.0.dmg": "foo 1.3.0",
+	})
+	const cask = `cask "foo" do
+  version "%[1]s"
+  sha256 "%[2]s"
+
+  url "%[3]s/#{version.csv.second}/Foo-#{version.csv.first}.dmg"
+  app "Foo.app"
+end
+`
+	f := newFormulaFixture(t, fmt.Sprintf(cask, "1.2.3,100", sha256Hex("foo 1.2.3"), srv.URL))
+	updated := f.apply(&updater.Update{
+		Path:     srv.URL + "/#{version.csv.second}/Foo-#{version.csv.first}.dmg",
+		Previous: "1.2.3,100",
+		Next:     "1.3.0,120",
+	})
+	assert.Equal(t, fmt.Sprintf(cask, "1.3.0,120", sha256Hex("foo 1.3.0"), srv.URL), updated)
+}
+
+func TestUpdater_Update_CaskNoCheck(t *testing.T) {
+	const cask = `cask "foo" do
+  version "%s"
+  sha256 :no_check
+
+  url "https://example.com/#{version.major}/Foo.dmg"
+  app "Foo.app"
+end
+`
+	f := newFormulaFixture(t, fmt.Sprintf(cask, "1.2.3"))
+	updated := f.apply(&updater.Update{
+		Path:     "https://example.com/#{version.major}/Foo.dmg",
+		Previous: "1.2.3",
+		Next:     "2.0.0",
+	})
+	assert.Equal(t, fmt.Sprintf(cask, "2.0.0"), updated)
+}
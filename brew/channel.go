@@ -0,0 +1,72 @@
+package brew
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Channel is the least stable kind of release a dependency may be updated to.
+type Channel int
+
+const (
+	// ChannelStable only updates to final releases.
+	ChannelStable Channel = iota
+	// ChannelRC also updates to release candidates like 1.2.0-rc1.
+	ChannelRC
+	// ChannelBeta updates to any prerelease, including alphas, betas and previews.
+	ChannelBeta
+)
+
+var channelNames = []string{"stable", "rc", "beta"}
+
+func (c Channel) String() string {
+	return channelNames[c]
+}
+
+func parseChannel(name string) (Channel, error) {
+	for i, n := range channelNames {
+		if n == name {
+			return Channel(i), nil
+		}
+	}
+	return ChannelStable, fmt.Errorf("unknown channel %q, expected one of %s", name, strings.Join(channelNames, ", "))
+}
+
+// prereleaseRe finds prerelease markers following the numeric part of a version: 1.2.0-rc1, 1.22beta2, 2.0.0-alpha.1,
+// and Python's short forms like 2.0b1.
+var prereleaseRe = regexp.MustCompile(`(?i)(?:\d|[._+~-])(alpha|beta|dev|preview|pre|rc|cr)(?:[._-]?\d+)*(?:$|[._+-])|\d(a|b)\d+$`)
+
+// versionChannel returns the channel a version is released to.
+func versionChannel(version string) Channel {
+	if m := prereleaseRe.FindStringSubmatch(version); m != nil {
+		switch strings.ToLower(m[1] + m[2]) {
+		case "rc", "cr":
+			return ChannelRC
+		default:
+			return ChannelBeta
+		}
+	}
+	// Other semver prereleases like 1.2.0-0.3.7 are assumed to be unstable:
+	if sv := semverIsh(csvFirst(version)); sv != "" && semver.Prerelease(sv) != "" {
+		return ChannelBeta
+	}
+	return ChannelStable
+}
+
+// releaseChannel returns the channel of a GitHub release. Releases flagged as prereleases are betas, unless their
+// tag marks them as release candidates.
+func releaseChannel(tag string, prerelease bool) Channel {
+	c := versionChannel(tag)
+	if prerelease && c == ChannelStable {
+		return ChannelBeta
+	}
+	return c
+}
+
+// allows returns true if a version is released to the channel, or a more stable one.
+func (c Channel) allows(version string) bool {
+	return versionChannel(version) <= c
+}
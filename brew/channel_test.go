@@ -0,0 +1,75 @@
+package brew
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionChannel(t *testing.T) {
+	cases := map[string]Channel{
+		"1.2.3":          ChannelStable,
+		"1.1.1w":         ChannelStable,
+		"2024.05.01":     ChannelStable,
+		"r123":           ChannelStable,
+		"1.2.3,4567":     ChannelStable,
+		"1.2.0-rc1":      ChannelRC,
+		"v1.2.0-RC.2":    ChannelRC,
+		"1.22rc1":        ChannelRC,
+		"1.22beta2":      ChannelBeta,
+		"2.0.0-alpha.1":  ChannelBeta,
+		"3.0.0-preview3": ChannelBeta,
+		"1.0.0-dev":      ChannelBeta,
+		"1.0.0-0.3.7":    ChannelBeta,
+		"2.0b1":          ChannelBeta,
+		"2.0.0a3":        ChannelBeta,
+		"1.0.2a":         ChannelStable,
+	}
+	for version, expected := range cases {
+		t.Run(version, func(t *testing.T) {
+			assert.Equal(t, expected, versionChannel(version))
+		})
+	}
+}
+
+func TestUpdater_Check_Channel(t *testing.T) {
+	gh := newFakeGitHub(t)
+	gh.json("/api/repos/foo/foo/releases", []*github.RepositoryRelease{
+		{TagName: github.String("v4.0.0"), Draft: github.Bool(true)},
+		{TagName: github.String("v3.0.0-beta1")},
+		{TagName: github.String("v2.1.0"), Prerelease: github.Bool(true)},
+		{TagName: github.String("v2.0.0-rc1"), Prerelease: github.Bool(true)},
+		{TagName: github.String("v1.5.0")},
+		{TagName: github.String("v1.0.0")},
+	})
+
+	cases := map[string]struct {
+		channel   Channel
+		directive string
+		version   string
+		next      string
+	}{
+		"stable":             {next: "1.5.0"},
+		"rc":                 {channel: ChannelRC, next: "2.0.0-rc1"},
+		"beta":               {channel: ChannelBeta, next: "3.0.0-beta1"},
+		"directive":          {directive: "# update: channel=rc", next: "2.0.0-rc1"},
+		"directive stable":   {channel: ChannelBeta, directive: "# update: channel=stable", next: "1.5.0"},
+		"current prerelease": {version: "1.0.0-rc1", next: "2.0.0-rc1"},
+	}
+	for label, tc := range cases {
+		t.Run(label, func(t *testing.T) {
+			version := tc.version
+			if version == "" {
+				version = "1.0.0"
+			}
+			f := newFormulaFixture(t, fmt.Sprintf("class Foo < Formula\n  %s\n  url \"https://github.com/foo/foo/releases/download/v#{version}/foo.tar.gz\"\n  version \"%s\"\nend\n", tc.directive, version), WithChannel(tc.channel))
+			f.ghRepos = gh.client.Repositories
+			update := f.check()
+			require.NotNil(t, update)
+			assert.Equal(t, tc.next, update.Next)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package brew
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/thepwagner/action-update/updater"
+)
+
+const defaultCratesURL = "https://crates.io"
+
+// cratesUserAgent identifies requests to the crates.io API, which rejects requests without a user agent.
+const cratesUserAgent = "action-update-brewformula (https://github.com/thepwagner/action-update-brewformula)"
+
+type cratesCrate struct {
+	Versions []struct {
+		Num       string    `json:"num"`
+		Checksum  string    `json:"checksum"`
+		Yanked    bool      `json:"yanked"`
+		CreatedAt time.Time `json:"created_at"`
+	} `json:"versions"`
+}
+
+// crateRe matches the path of a crate download, e.g. /crates/ripgrep/ripgrep-14.1.0.crate on static.crates.io or
+// /api/v1/crates/ripgrep/14.1.0/download on crates.io.
+var crateRe = regexp.MustCompile(`^(?:/crates/([^/]+)/[^/]+\.crate|/api/v1/crates/([^/]+)/[^/]+/download)$`)
+
+// cratePackage identifies the crate of a download url.
+func (u Updater) cratePackage(rawURL string) (string, bool) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	base, err := url.Parse(u.cratesURL)
+	if err != nil {
+		return "", false
+	}
+	if parsed.Host != "static.crates.io" && parsed.Host != base.Host {
+		return "", false
+	}
+	m := crateRe.FindStringSubmatch(parsed.Path)
+	if m == nil {
+		return "", false
+	}
+	return m[1] + m[2], true
+}
+
+// cratesSource discovers Rust crates published to crates.io.
+type cratesSource struct{ u *Updater }
+
+func (s cratesSource) Match(dep updater.Dependency) bool {
+	_, ok := s.u.cratePackage(expandVersion(dep.Path, dep.Version))
+	return ok
+}
+
+func (s cratesSource) ListVersions(ctx context.Context, q VersionQuery) ([]SourceVersion, error) {
+	name, _ := s.u.cratePackage(expandVersion(q.Path, q.Version))
+	crate, err := s.u.fetchCrate(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]SourceVersion, 0, len(crate.Versions))
+	for _, v := range crate.Versions {
+		if v.Yanked {
+			continue
+		}
+		versions = append(versions, SourceVersion{Version: v.Num, Published: v.CreatedAt})
+	}
+	logrus.WithFields(logrus.Fields{
+		"crate":    name,
+		"versions": len(versions),
+	}).Debug("fetched crate versions")
+	return versions, nil
+}
+
+// ResolveHash returns the checksum the registry lists for the next version's crate, without downloading it.
+func (s cratesSource) ResolveHash(ctx context.Context, update updater.Update, oldHash string) (string, error) {
+	if len(oldHash) != 64 {
+		return "", fmt.Errorf("crates.io does not list a checksum like %q", oldHash)
+	}
+	name, _ := s.u.cratePackage(expandVersion(update.Path, update.Previous))
+	crate, err := s.u.fetchCrate(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	next := nextVersion(update)
+	for _, v := range crate.Versions {
+		if v.Num == next && !v.Yanked {
+			return v.Checksum, nil
+		}
+	}
+	return "", fmt.Errorf("crate %s has no version %s", name, next)
+}
+
+func (u Updater) fetchCrate(ctx context.Context, name string) (*cratesCrate, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/crates/%s", strings.TrimSuffix(u.cratesURL, "/"), url.PathEscape(name)), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", cratesUserAgent)
+	res, err := u.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching crate %s: unexpected status %d", name, res.StatusCode)
+	}
+
+	var crate cratesCrate
+	if err := json.NewDecoder(res.Body).Decode(&crate); err != nil {
+		return nil, fmt.Errorf("decoding crate %s: %w", name, err)
+	}
+	return &crate, nil
+}
@@ -0,0 +1,64 @@
+package brew
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdater_Crates(t *testing.T) {
+	checksums := map[string]string{
+		"1.0.0": strings.Repeat("a", 64),
+		"1.1.0": strings.Repeat("b", 64),
+		"1.2.0": strings.Repeat("c", 64),
+	}
+	srv := newFakeServer(t, nil)
+	srv.mux.HandleFunc("/api/v1/crates/foo", func(w http.ResponseWriter, r *http.Request) {
+		// crates.io rejects requests without a user agent:
+		if r.UserAgent() == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		versions := make([]map[string]interface{}, 0, len(checksums))
+		for v, checksum := range checksums {
+			versions = append(versions, map[string]interface{}{
+				"num":        v,
+				"checksum":   checksum,
+				"yanked":     v == "1.2.0",
+				"created_at": "2023-01-01T00:00:00Z",
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"versions": versions})
+	})
+
+	const formula = "class Foo < Formula\n  url \"%s/crates/foo/foo-%s.crate\"\n  sha256 \"%s\"\nend\n"
+	f := newFormulaFixture(t, fmt.Sprintf(formula, srv.URL, "1.0.0", checksums["1.0.0"]), WithCrates(srv.URL))
+
+	// 1.2.0 was yanked:
+	update := f.check()
+	require.NotNil(t, update)
+	assert.Equal(t, "1.1.0", update.Next)
+	assert.Equal(t, fmt.Sprintf(formula, srv.URL, "1.1.0", checksums["1.1.0"]), f.apply(update))
+}
+
+func TestUpdater_CratePackage(t *testing.T) {
+	u := NewUpdater("")
+	cases := map[string]string{
+		"https://static.crates.io/crates/ripgrep/ripgrep-14.1.0.crate": "ripgrep",
+		"https://crates.io/api/v1/crates/ripgrep/14.1.0/download":      "ripgrep",
+		"https://example.com/crates/ripgrep/ripgrep-14.1.0.crate":      "",
+		"https://static.crates.io/ripgrep-14.1.0.tar.gz":               "",
+	}
+	for rawURL, expected := range cases {
+		t.Run(rawURL, func(t *testing.T) {
+			name, ok := u.cratePackage(rawURL)
+			assert.Equal(t, expected != "", ok)
+			assert.Equal(t, expected, name)
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package brew
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/thepwagner/action-update/updater"
+)
+
+// formulaDep is a dependency located within a parsed formula: the formula's own source, or one of its resources.
+type formulaDep struct {
+	updater.Dependency
+	// file is the path of the formula relative to the updater's root, set when the dependency is located by the updater.
+	file string
+	// scope is the block holding the dependency's stanzas.
+	scope *Block
+	// artifacts are the url/sha256 pairs of the dependency. Binary formulas may declare one per platform.
+	artifacts []*artifact
+
+	// tag and revision are set for git dependencies: `url "https://github.com/o/r.git", tag: "v1.2.3", revision: "..."`
+	tag, revision *Literal
+	// tagPrefix precedes the version in the tag, e.g. "v".
+	tagPrefix string
+
+	directives directives
+	// err is set when the dependency's directives are invalid, and is reported when checking the dependency.
+	err error
+}
+
+// artifact is a url and the checksums declared alongside it.
+type artifact struct {
+	// platform is the path of platform blocks declaring the artifact, e.g. "on_macos/on_arm".
+	platform string
+	scope    *Block
+	stanza   *Stanza
+	url      Literal
+	mirrors  []Literal
+	hashes   []Literal
+}
+
+// gitTagVersionRe splits a tag into a prefix and version, e.g. "release-1.2.3".
+var gitTagVersionRe = regexp.MustCompile(`^(\D*)(\d.*)$`)
+
+// platformBlocks declare artifacts specific to an operating system or architecture.
+var platformBlocks = map[string]bool{
+	"on_arm":   true,
+	"on_intel": true,
+	"on_linux": true,
+	"on_macos": true,
+}
+
+// conditionalBlocks declare an artifact per branch, e.g. `if Hardware::CPU.arm?` within a platform block.
+var conditionalBlocks = map[string]bool{
+	"case":   true,
+	"if":     true,
+	"unless": true,
+}
+
+func (d *formulaDep) resource() bool {
+	return d.scope.Kind == "resource"
+}
+
+func (d *formulaDep) git() bool {
+	return d.tag != nil
+}
+
+// tagVersion extracts the version from one of a git dependency's tags, returning false for unrelated tags.
+func (d *formulaDep) tagVersion(tag string) (string, bool) {
+	if d.directives.tagRegex != nil {
+		return d.directives.tagVersion(tag)
+	}
+	m := gitTagVersionRe.FindStringSubmatch(tag)
+	if m == nil || m[1] != d.tagPrefix {
+		return "", false
+	}
+	return m[2], true
+}
+
+// matches returns true if the update applies to this dependency.
+func (d *formulaDep) matches(update updater.Update) bool {
+	return d.Path == update.Path && d.Version == update.Previous
+}
+
+// formulaDeps returns the dependencies of a formula: the main source, then each independently versioned resource.
+func formulaDeps(f *Formula) []*formulaDep {
+	var deps []*formulaDep
+	main := f.Main()
+	mainComments := main.Comments
+	if main != f.Root {
+		mainComments = append(append([]*Comment{}, f.Root.Comments...), mainComments...)
+	}
+	if dep := directedDep(main, mainComments); dep != nil {
+		deps = append(deps, dep)
+	}
+
+	for _, resource := range main.Children("resource") {
+		if dep := directedDep(resource, resource.Comments); dep != nil {
+			if dep.err != nil {
+				dep.err = fmt.Errorf("resource %q: %w", resource.Name, dep.err)
+			}
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+// directedDep finds the dependency declared in a block, and applies the directives from its comments.
+// Invalid directives don't prevent locating the dependency, they are recorded as the dependency's err.
+func directedDep(scope *Block, comments []*Comment) *formulaDep {
+	dep := scopeDep(scope)
+	if dep == nil {
+		return nil
+	}
+	d, err := parseDirectives(comments)
+	if err != nil {
+		dep.err = err
+		return dep
+	}
+	dep.directives = d
+
+	if dep.git() && d.tagRegex != nil {
+		version, ok := d.tagVersion(dep.tag.Value)
+		if !ok {
+			dep.err = fmt.Errorf("tag-regex %q does not match tag %q", d.tagRegex, dep.tag.Value)
+			return dep
+		}
+		dep.Version = version
+	}
+	return dep
+}
+
+// scopeDep finds the artifacts declared in a block, and the version of those artifacts.
+func scopeDep(scope *Block) *formulaDep {
+	artifacts := scopeArtifacts(scope, "")
+	if len(artifacts) == 0 {
+		return nil
+	}
+	first := artifacts[0]
+	dep := &formulaDep{
+		Dependency: updater.Dependency{Path: first.url.Value},
+		scope:      scope,
+		artifacts:  artifacts,
+	}
+
+	if tag, ok := first.stanza.Options["tag"]; ok && !tag.Symbol {
+		m := gitTagVersionRe.FindStringSubmatch(tag.Value)
+		if m == nil {
+			return nil
+		}
+		dep.Version = m[2]
+		dep.tag = &tag
+		dep.tagPrefix = m[1]
+		if revision, ok := first.stanza.Options["revision"]; ok && !revision.Symbol {
+			dep.revision = &revision
+		}
+		return dep
+	}
+
+	// https://foo.com/awesome-#{VERSION}.tar.gz
+	if interpolationRe.MatchString(first.url.Value) {
+		version, ok := formulaVersion(first.scope, first.url.Value)
+		switch {
+		case ok && version.Symbol:
+			// e.g. a cask's `version :latest`
+			return nil
+		case ok && (scope.Contains(version) || !dep.resource()):
+			dep.Version = version.Value
+			return dep
+		case dep.resource():
+			// Resources interpolating the formula's version are updated with the formula.
+			return nil
+		}
+	}
+
+	if version := urlVersion(first.url.Value); version != "" {
+		dep.Version = version
+		return dep
+	}
+
+	// version "1.2.3" with https://foo.com/awesome-1_2_3.tar.gz
+	if version, ok := scope.First("version").Arg(); ok && !version.Symbol {
+		for _, variant := range versionVariants(version.Value) {
+			if strings.Contains(first.url.Value, variant) {
+				dep.Version = version.Value
+				return dep
+			}
+		}
+	}
+	return nil
+}
+
+// versionSeparators may replace the dots of a version in urls and tags.
+var versionSeparators = []string{"_", "-"}
+
+// versionVariants returns the version with its dots replaced by other separators, as used by some urls and tags.
+func versionVariants(version string) []string {
+	var variants []string
+	for _, sep := range versionSeparators {
+		if v := strings.ReplaceAll(version, ".", sep); v != version {
+			variants = append(variants, v)
+		}
+	}
+	return variants
+}
+
+// scopeArtifacts returns the url declared in a block, followed by those declared in nested platform blocks and the
+// branches of nested conditionals.
+func scopeArtifacts(scope *Block, platform string) []*artifact {
+	var artifacts []*artifact
+	urlStanza := scope.First("url")
+	if urlArg, ok := urlStanza.Arg(); ok && !urlArg.Symbol {
+		artifacts = append(artifacts, &artifact{
+			platform: platform,
+			scope:    scope,
+			stanza:   urlStanza,
+			url:      urlArg,
+			mirrors:  scopeMirrors(scope),
+			hashes:   scopeHashes(scope),
+		})
+	}
+
+	for _, b := range scope.Blocks {
+		switch {
+		case platformBlocks[b.Kind]:
+			artifacts = append(artifacts, scopeArtifacts(b, nestedPlatform(platform, b.Kind))...)
+		case conditionalBlocks[b.Kind]:
+			for _, branch := range b.Branches() {
+				artifacts = append(artifacts, scopeArtifacts(branch, nestedPlatform(platform, branch.Kind))...)
+			}
+		}
+	}
+	return artifacts
+}
+
+// nestedPlatform returns the path of a block nested within a platform, e.g. "on_linux/if".
+func nestedPlatform(platform, kind string) string {
+	if platform == "" {
+		return kind
+	}
+	return strings.Join([]string{platform, kind}, "/")
+}
+
+// urlVersionRe matches candidate versions in a URL: dotted numbers, optionally with a letter suffix like 1.1.1w.
+var urlVersionRe = regexp.MustCompile(`\d+(?:\.\d+)+(?:[a-z]\b)?`)
+
+// urlVersion finds a version in the path of a URL, preferring the most specific.
+func urlVersion(rawURL string) string {
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.Host != "" {
+		rawURL = parsed.Path
+	}
+	var version string
+	for _, v := range urlVersionRe.FindAllString(rawURL, -1) {
+		if strings.Count(v, ".") > strings.Count(version, ".") {
+			version = v
+		}
+	}
+	return version
+}
+
+// scopeHashes returns the checksums declared directly within a block, ignoring nested blocks.
+func scopeHashes(scope *Block) []Literal {
+	var hashes []Literal
+	for _, s := range scope.Stanzas {
+		if s.Method != "sha256" && s.Method != "sha1" {
+			continue
+		}
+		if arg, ok := s.Arg(); ok && !arg.Symbol {
+			hashes = append(hashes, arg)
+		}
+	}
+	return hashes
+}
+
+// formulaVersion resolves the literal a URL template interpolates, e.g. #{version} or #{VERSION}.
+func formulaVersion(scope *Block, formulaURL string) (Literal, bool) {
+	for _, m := range interpolationRe.FindAllStringSubmatch(formulaURL, -1) {
+		name := m[1]
+		if name == "version" {
+			for s := scope; s != nil; s = s.Parent {
+				if arg, ok := s.First("version").Arg(); ok {
+					return arg, true
+				}
+			}
+			continue
+		}
+		if lit, ok := scope.Lookup(name); ok {
+			return lit, true
+		}
+	}
+	return Literal{}, false
+}
@@ -0,0 +1,34 @@
+package brew
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thepwagner/action-update/updater"
+)
+
+func TestUpdater_Update_Resource(t *testing.T) {
+	srv := newFakeServer(t, map[string]string{
+		"/foo-1.0.0.tar.gz": "foo 1.0.0",
+		"/bar-1.0.0.tar.gz": "bar 1.0.0",
+		"/bar-1.1.0.tar.gz": "bar 1.1.0",
+	})
+	f := newFormulaFixture(t, fmt.Sprintf(`class Foo < Formula
+  url "%[1]s/foo-1.0.0.tar.gz"
+  sha256 "%[2]s"
+
+  resource "bar" do
+    url "%[1]s/bar-1.0.0.tar.gz"
+    sha256 "%[3]s"
+  end
+end
+`, srv.URL, sha256Hex("foo 1.0.0"), sha256Hex("bar 1.0.0")))
+
+	formula := f.apply(&updater.Update{Path: srv.URL + "/bar-1.0.0.tar.gz", Previous: "1.0.0", Next: "1.1.0"})
+	assert.Contains(t, formula, srv.URL+"/foo-1.0.0.tar.gz")
+	assert.Contains(t, formula, sha256Hex("foo 1.0.0"))
+	assert.Contains(t, formula, srv.URL+"/bar-1.1.0.tar.gz")
+	assert.Contains(t, formula, sha256Hex("bar 1.1.0"))
+	assert.NotContains(t, formula, sha256Hex("bar 1.0.0"))
+}
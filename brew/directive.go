@@ -0,0 +1,176 @@
+package brew
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// directives are `# update: ...` comments controlling how a dependency is updated. Directives apply to the
+// dependency declared by the block containing them, comments before the formula's class apply to the formula:
+//
+//	# update: pin 2.x
+//	# update: source=github:owner/repo tag-regex=release-(.*)
+//	# update: tag=cli/v#{version}
+//	# update: channel=rc release-line=patch-first
+type directives struct {
+	// ignore skips the dependency entirely.
+	ignore bool
+	// pin restricts updates to versions matching a pattern like "2.x".
+	pin string
+	// source overrides where versions are discovered, e.g. "github:owner/repo" or "gitlab:group/project".
+	source string
+	// tagRegex extracts the version from a tag, using the first capture group.
+	tagRegex *regexp.Regexp
+	// tag maps between versions and tags in both directions, e.g. "cli/v#{version}".
+	tag *tagPattern
+	// scheme overrides the detected version scheme.
+	scheme VersionScheme
+	// channel overrides the configured release channel.
+	channel *Channel
+	// releaseLine overrides the configured release line mode.
+	releaseLine *ReleaseLine
+}
+
+var directiveRe = regexp.MustCompile(`^#\s*update:(.*)$`)
+
+// parseDirectives reads the directives from a block's comments.
+func parseDirectives(comments []*Comment) (directives, error) {
+	var d directives
+	for _, c := range comments {
+		m := directiveRe.FindStringSubmatch(strings.TrimSpace(c.Text))
+		if m == nil {
+			continue
+		}
+		fields := strings.Fields(m[1])
+		if len(fields) == 0 {
+			return d, fmt.Errorf("line %d: empty update directive", c.Line)
+		}
+		for i := 0; i < len(fields); i++ {
+			raw := fields[i]
+			key, value := raw, ""
+			if eq := strings.IndexByte(key, '='); eq >= 0 {
+				key, value = key[:eq], key[eq+1:]
+			} else if key == "pin" && i+1 < len(fields) {
+				// `pin 2.x` reads as naturally as `pin=2.x`
+				i++
+				value = fields[i]
+				raw += " " + value
+			}
+			if err := d.set(key, value); err != nil {
+				return d, fmt.Errorf("line %d: invalid update directive %q: %w", c.Line, raw, err)
+			}
+		}
+	}
+	return d, nil
+}
+
+func (d *directives) set(key, value string) error {
+	switch key {
+	case "ignore":
+		if value != "" {
+			return fmt.Errorf("ignore does not take a value")
+		}
+		d.ignore = true
+	case "pin":
+		if value == "" {
+			return fmt.Errorf("pin requires a version pattern like 2.x")
+		}
+		d.pin = value
+	case "source":
+		kind, repo := splitSource(value)
+		valid := strings.Contains(repo, "/") && !strings.HasPrefix(repo, "/") && !strings.HasSuffix(repo, "/")
+		switch {
+		case kind == "github" && valid && strings.Count(repo, "/") == 1:
+		case kind == "gitlab" && valid:
+		default:
+			return fmt.Errorf("source must be github:owner/repo or gitlab:group/project")
+		}
+		d.source = value
+	case "tag-regex":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return err
+		}
+		if re.NumSubexp() < 1 {
+			return fmt.Errorf("tag-regex must capture the version")
+		}
+		d.tagRegex = re
+	case "tag":
+		p, err := parseTagPattern(value)
+		if err != nil {
+			return err
+		}
+		d.tag = p
+	case "scheme":
+		scheme, err := lookupVersionScheme(value)
+		if err != nil {
+			return err
+		}
+		d.scheme = scheme
+	case "channel":
+		ch, err := parseChannel(value)
+		if err != nil {
+			return err
+		}
+		d.channel = &ch
+	case "release-line":
+		l, err := parseReleaseLine(value)
+		if err != nil {
+			return err
+		}
+		d.releaseLine = &l
+	default:
+		return fmt.Errorf("unknown directive %q", key)
+	}
+	return nil
+}
+
+func splitSource(source string) (kind, location string) {
+	if i := strings.IndexByte(source, ':'); i >= 0 {
+		return source[:i], source[i+1:]
+	}
+	return "", source
+}
+
+// tagVersion extracts a version from a tag using the tag-regex directive, returning false if the tag doesn't match.
+// Without a directive, the tag is returned unchanged.
+func (d directives) tagVersion(tag string) (string, bool) {
+	if d.tagRegex == nil {
+		return tag, true
+	}
+	m := d.tagRegex.FindStringSubmatch(tag)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// allowed removes the versions excluded by the pin directive.
+func (d directives) allowed(versions []string) []string {
+	ret := make([]string, 0, len(versions))
+	for _, v := range versions {
+		if d.pinned(v) {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// pinned returns true if the pin directive allows the version. Each component of the pin must match, until an
+// "x" or "*" wildcard.
+func (d directives) pinned(version string) bool {
+	if d.pin == "" {
+		return true
+	}
+	parts := strings.FieldsFunc(strings.TrimPrefix(csvFirst(version), "v"), func(r rune) bool { return r == '.' || r == '-' })
+	for i, p := range strings.Split(strings.TrimPrefix(d.pin, "v"), ".") {
+		if p == "x" || p == "*" {
+			return true
+		}
+		if i >= len(parts) || parts[i] != p {
+			return false
+		}
+	}
+	return true
+}
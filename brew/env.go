@@ -1,17 +1,109 @@
 package brew
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
 	"github.com/thepwagner/action-update/actions/updateaction"
 	"github.com/thepwagner/action-update/updater"
 )
 
 type Environment struct {
 	updateaction.Environment
-	GPG bool `env:"INPUT_GPG" envDefault:"false"`
+	GPG               bool   `env:"INPUT_GPG" envDefault:"false"`
+	BottlePlaceholder string `env:"INPUT_BOTTLE_PLACEHOLDER"`
+	VersionSchemes    string `env:"INPUT_VERSION_SCHEMES"`
+	Channel           string `env:"INPUT_CHANNEL" envDefault:"stable"`
+	ReleaseLine       string `env:"INPUT_RELEASE_LINE" envDefault:"latest"`
+	MinAge            string `env:"INPUT_MIN_AGE"`
+	GitLabURL         string `env:"INPUT_GITLAB_URL" envDefault:"https://gitlab.com"`
+	GitLabToken       string `env:"INPUT_GITLAB_TOKEN"`
+	PyPIURL           string `env:"INPUT_PYPI_URL" envDefault:"https://pypi.org"`
+	NPMURL            string `env:"INPUT_NPM_URL" envDefault:"https://registry.npmjs.org"`
+	CratesURL         string `env:"INPUT_CRATES_URL" envDefault:"https://crates.io"`
+	RubyGemsURL       string `env:"INPUT_RUBYGEMS_URL" envDefault:"https://rubygems.org"`
+	GNUMirror         string `env:"INPUT_GNU_MIRROR"`
+	MavenURL          string `env:"INPUT_MAVEN_URL" envDefault:"https://repo1.maven.org/maven2"`
+	HashiCorpURL      string `env:"INPUT_HASHICORP_URL" envDefault:"https://releases.hashicorp.com"`
+	HashiCorpKeyURL   string `env:"INPUT_HASHICORP_KEY_URL" envDefault:"https://www.hashicorp.com/.well-known/pgp-key.txt"`
 }
 
 func (e *Environment) NewUpdater(root string) updater.Updater {
-	u := NewUpdater(root, WithGPG(e.GPG))
+	opts := append([]UpdaterOpt{
+		WithGPG(e.GPG),
+		WithBottlePlaceholder(e.BottlePlaceholder),
+		WithGitLab(e.GitLabURL, e.GitLabToken),
+		WithPyPI(e.PyPIURL),
+		WithNPM(e.NPMURL),
+		WithCrates(e.CratesURL),
+		WithRubyGems(e.RubyGemsURL),
+		WithGNUMirror(e.GNUMirror),
+		WithMaven(e.MavenURL),
+		WithHashiCorp(e.HashiCorpURL, e.HashiCorpKeyURL),
+	}, e.versionSchemes()...)
+	if ch, err := parseChannel(e.Channel); err != nil {
+		logrus.WithError(err).Warn("ignoring channel, updating to stable releases")
+	} else {
+		opts = append(opts, WithChannel(ch))
+	}
+	if l, err := parseReleaseLine(e.ReleaseLine); err != nil {
+		logrus.WithError(err).Warn("ignoring release line, updating to the latest version")
+	} else {
+		opts = append(opts, WithReleaseLine(l))
+	}
+	age, err := parseMinAge(e.MinAge)
+	if err != nil {
+		return invalidUpdater{err: fmt.Errorf("invalid min_age: %w", err)}
+	}
+	opts = append(opts, WithMinAge(age))
+	u := NewUpdater(root, opts...)
 	u.pathFilter = e.Ignored
 	return u
 }
+
+// versionSchemes parses lines of `path/glob.rb: scheme`.
+func (e *Environment) versionSchemes() (opts []UpdaterOpt) {
+	for _, line := range strings.Split(e.VersionSchemes, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		i := strings.LastIndex(line, ":")
+		if i < 0 {
+			logrus.WithField("line", line).Warn("ignoring version scheme, expected `path/glob.rb: scheme`")
+			continue
+		}
+		pattern, name := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		scheme, err := lookupVersionScheme(name)
+		if err != nil {
+			logrus.WithError(err).WithField("pattern", pattern).Warn("ignoring version scheme")
+			continue
+		}
+		opts = append(opts, WithVersionScheme(pattern, scheme))
+	}
+	return
+}
+
+// invalidUpdater is created from invalid inputs. updater.Factory can't return an error, so every operation fails with
+// it instead.
+type invalidUpdater struct {
+	err error
+}
+
+func (u invalidUpdater) Name() string {
+	return "brew"
+}
+
+func (u invalidUpdater) Dependencies(context.Context) ([]updater.Dependency, error) {
+	return nil, u.err
+}
+
+func (u invalidUpdater) Check(context.Context, updater.Dependency, func(string) bool) (*updater.Update, error) {
+	return nil, u.err
+}
+
+func (u invalidUpdater) ApplyUpdate(context.Context, updater.Update) error {
+	return u.err
+}
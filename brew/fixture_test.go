@@ -0,0 +1,114 @@
+package brew
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thepwagner/action-update/updater"
+)
+
+// fakeServer serves files by path. Tests register other responses on its mux.
+type fakeServer struct {
+	*httptest.Server
+	mux   *http.ServeMux
+	files map[string]string
+}
+
+func newFakeServer(t *testing.T, files map[string]string) *fakeServer {
+	f := &fakeServer{mux: http.NewServeMux(), files: map[string]string{}}
+	for p, contents := range files {
+		f.files[p] = contents
+	}
+	f.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		contents, ok := f.files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, contents)
+	})
+	f.Server = httptest.NewServer(f.mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeServer) file(path, contents string) {
+	f.files[path] = contents
+}
+
+func (f *fakeServer) json(path string, v interface{}) {
+	f.mux.HandleFunc(path, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	})
+}
+
+func sha256Hex(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
+}
+
+// parseFormulaDeps parses a formula and returns its dependencies.
+func parseFormulaDeps(formula string) ([]updater.Dependency, error) {
+	f, err := parseFormula(formula)
+	if err != nil {
+		return nil, err
+	}
+	var deps []updater.Dependency
+	for _, dep := range formulaDeps(f) {
+		deps = append(deps, dep.Dependency)
+	}
+	return deps, nil
+}
+
+// formulaFixture is a formula written to a temporary directory, with an updater for that directory.
+type formulaFixture struct {
+	*Updater
+	t    *testing.T
+	path string
+}
+
+// newFormulaFixture writes the formula to foo.rb in a temporary directory.
+func newFormulaFixture(t *testing.T, formula string, opts ...UpdaterOpt) *formulaFixture {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "foo.rb")
+	require.NoError(t, ioutil.WriteFile(path, []byte(formula), 0600))
+	return &formulaFixture{Updater: NewUpdater(dir, opts...), t: t, path: path}
+}
+
+// deps returns the dependencies of the formula, requiring there are n.
+func (f *formulaFixture) deps(n int) []updater.Dependency {
+	deps, err := f.Dependencies(context.Background())
+	require.NoError(f.t, err)
+	require.Len(f.t, deps, n)
+	return deps
+}
+
+// check checks the formula's only dependency for an update.
+func (f *formulaFixture) check() *updater.Update {
+	update, err := f.Check(context.Background(), f.deps(1)[0], nil)
+	require.NoError(f.t, err)
+	return update
+}
+
+// apply applies an update, returning the updated formula.
+func (f *formulaFixture) apply(update *updater.Update) string {
+	require.NotNil(f.t, update)
+	require.NoError(f.t, f.ApplyUpdate(context.Background(), *update))
+	return f.read()
+}
+
+// read returns the current contents of the formula.
+func (f *formulaFixture) read() string {
+	b, err := ioutil.ReadFile(f.path)
+	require.NoError(f.t, err)
+	return string(b)
+}
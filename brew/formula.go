@@ -1,49 +1,552 @@
 package brew
 
 import (
-	"regexp"
-
-	"github.com/thepwagner/action-update/updater"
+	"fmt"
+	"sort"
+	"strings"
 )
 
-var (
-	urlRe           = regexp.MustCompile(`url ["'](.*)["']`)
-	shasumRe        = regexp.MustCompile(`sha(1|256) ["'](.*)["']`)
-	versionVarRe    = regexp.MustCompile(`(?i)version\s*=?\s+["'](.*)["']`)
-	versionTemplate = regexp.MustCompile(`(?i)#{version}`)
-	semverRe        = regexp.MustCompile(`\d+\.\d+\.\d+`)
-)
+// Formula is the parsed structure of a formula file.
+type Formula struct {
+	Source string
+	Root   *Block
+}
+
+// Block is a region of a formula closed by `end`: a `do ... end` block, or a class, def, if etc.
+type Block struct {
+	// Kind is the method (e.g. "resource") or keyword (e.g. "class") that opened the block.
+	Kind string
+	// Name is the first string argument of the method that opened the block, or the name of a class.
+	Name string
+	// Stanza is the statement that opened a `do` block.
+	Stanza *Stanza
+	// Start and End are the byte offsets of the whole block, from the opening statement to `end`.
+	Start, End int
+
+	Parent      *Block
+	Blocks      []*Block
+	Stanzas     []*Stanza
+	Assignments []*Assignment
+	Comments    []*Comment
+	// Clauses divide a conditional block into branches.
+	Clauses []*Clause
+}
+
+// Stanza is a method call statement with literal arguments, e.g. `url "https://..."` or `sha256 "..."`.
+type Stanza struct {
+	Method  string
+	Args    []Literal
+	Options map[string]Literal
+	// Start and End are the byte offsets of the statement.
+	Start, End int
+	Line       int
+}
+
+// Literal is a string, symbol, number or regular expression argument.
+type Literal struct {
+	// Value is the source text of the literal, without delimiters. Escapes and interpolation are not evaluated.
+	Value  string
+	Symbol bool
+	Regexp bool
+	Flags  string
+	// Start and End are the byte offsets of Value.
+	Start, End int
+}
+
+// Assignment is a constant or variable assigned a literal, e.g. `VERSION = "1.2.3"`.
+type Assignment struct {
+	Name       string
+	Value      Literal
+	Start, End int
+}
+
+// Clause is an `elsif`, `else` or `when` statement, starting a branch of a conditional block.
+type Clause struct {
+	Keyword string
+	Start   int
+}
+
+// Comment is a `#` comment.
+type Comment struct {
+	Text       string
+	Start, End int
+	Line       int
+}
+
+// Arg returns the first positional argument of the stanza.
+func (s *Stanza) Arg() (Literal, bool) {
+	if s == nil || len(s.Args) == 0 {
+		return Literal{}, false
+	}
+	return s.Args[0], true
+}
+
+// Find returns stanzas calling the given method directly within this block.
+func (b *Block) Find(method string) []*Stanza {
+	var ret []*Stanza
+	for _, s := range b.Stanzas {
+		if s.Method == method {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// First returns the first stanza calling the given method directly within this block.
+func (b *Block) First(method string) *Stanza {
+	for _, s := range b.Stanzas {
+		if s.Method == method {
+			return s
+		}
+	}
+	return nil
+}
+
+// Children returns blocks of the given kind directly within this block.
+func (b *Block) Children(kind string) []*Block {
+	var ret []*Block
+	for _, c := range b.Blocks {
+		if c.Kind == kind {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// Contains returns true if the literal is within this block.
+func (b *Block) Contains(l Literal) bool {
+	return b.Start <= l.Start && l.End <= b.End
+}
 
-func parseFormulaDeps(formula string) ([]updater.Dependency, error) {
-	// Find `VERSION =` variables and `url` string:
-	versionVar := versionVarRe.FindStringSubmatch(formula)
-	urlMatch := urlRe.FindAllStringSubmatch(formula, -1)
-	if len(urlMatch) != 1 {
-		return nil, nil
+// Lookup resolves a constant or variable, searching enclosing blocks.
+func (b *Block) Lookup(name string) (Literal, bool) {
+	for s := b; s != nil; s = s.Parent {
+		for _, a := range s.Assignments {
+			if a.Name == name {
+				return a.Value, true
+			}
+		}
 	}
-	formulaURL := urlMatch[0][1]
+	return Literal{}, false
+}
+
+// Branches splits a conditional block at its clauses. Each branch is a block holding the stanzas, assignments and
+// nested blocks within it, whose Kind is the keyword opening the branch.
+func (b *Block) Branches() []*Block {
+	branches := []*Block{{Kind: b.Kind, Start: b.Start}}
+	for _, c := range b.Clauses {
+		branches[len(branches)-1].End = c.Start
+		branches = append(branches, &Block{Kind: c.Keyword, Start: c.Start})
+	}
+	branches[len(branches)-1].End = b.End
+
+	for _, branch := range branches {
+		branch.Parent = b.Parent
+		for _, s := range b.Stanzas {
+			if branch.Start <= s.Start && s.Start < branch.End {
+				branch.Stanzas = append(branch.Stanzas, s)
+			}
+		}
+		for _, a := range b.Assignments {
+			if branch.Start <= a.Start && a.Start < branch.End {
+				branch.Assignments = append(branch.Assignments, a)
+			}
+		}
+		for _, nested := range b.Blocks {
+			if branch.Start <= nested.Start && nested.Start < branch.End {
+				branch.Blocks = append(branch.Blocks, nested)
+			}
+		}
+	}
+	return branches
+}
+
+// Main returns the block holding the formula's own stanzas: the formula class, the cask, or the file itself.
+func (f *Formula) Main() *Block {
+	for _, b := range f.Root.Blocks {
+		if b.Kind == "class" || b.Kind == "cask" {
+			return b
+		}
+	}
+	return f.Root
+}
+
+func parseFormula(src string) (*Formula, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{
+		src:    src,
+		tokens: tokens,
+		root:   &Block{Start: 0, End: len(src)},
+	}
+	p.scope = p.root
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return &Formula{Source: src, Root: p.root}, nil
+}
+
+// blockKeywords open a block that is closed by `end`.
+var blockKeywords = map[string]bool{
+	"begin": true, "case": true, "class": true, "def": true, "for": true, "if": true, "module": true,
+	"unless": true, "until": true, "while": true,
+}
+
+// clauseKeywords continue an open block rather than calling a method.
+var clauseKeywords = map[string]bool{
+	"else": true, "ensure": true, "rescue": true,
+}
+
+// branchKeywords start another branch of a conditional block.
+var branchKeywords = map[string]bool{
+	"else": true, "elsif": true, "when": true,
+}
+
+// modifierKeywords make the statement before them conditional, e.g. `begin ... end while x`.
+var modifierKeywords = map[string]bool{
+	"if": true, "rescue": true, "unless": true, "until": true, "while": true,
+}
 
-	// https://foo.com/awesome-#{VERSION}.tar.gz
-	if len(versionVar) > 0 && versionTemplate.MatchString(formulaURL) {
-		return []updater.Dependency{{
-			Path:    formulaURL,
-			Version: versionVar[1],
-		}}, nil
+// continuations are operators that continue a statement onto the next line.
+var continuations = map[string]bool{
+	",": true, ".": true, "&.": true, "(": true, "[": true, "{": true, "|": true, "||": true, "&&": true,
+	"+": true, "-": true, "*": true, "=": true, "=>": true, "?": true, ":": true, "<<": true, "==": true,
+	"||=": true, "&&=": true, "+=": true, "\\": true,
+}
+
+type parser struct {
+	src    string
+	tokens []token
+	pos    int
+	root   *Block
+	scope  *Block
+}
+
+func (p *parser) parse() error {
+	for p.pos < len(p.tokens) {
+		switch t := p.tokens[p.pos]; {
+		case t.kind == tokNewline || t.is(tokPunct, ";"):
+			p.pos++
+		case t.kind == tokComment:
+			p.comment(t)
+			p.pos++
+		default:
+			if err := p.statement(p.collect()); err != nil {
+				return err
+			}
+		}
 	}
 
-	if version := semverRe.FindString(formulaURL); version != "" {
-		return []updater.Dependency{{
-			Path:    formulaURL,
-			Version: version,
-		}}, nil
+	if p.scope != p.root {
+		return fmt.Errorf("line %d: unterminated %q block", p.blockLine(p.scope), p.scope.Kind)
 	}
+	return nil
+}
 
-	return nil, nil
+func (p *parser) comment(t token) {
+	p.scope.Comments = append(p.scope.Comments, &Comment{Text: t.text, Start: t.start, End: t.end, Line: t.line})
+}
+
+func (p *parser) blockLine(b *Block) int {
+	return strings.Count(p.src[:b.Start], "\n") + 1
+}
+
+// collect gathers the tokens of the next statement. A statement ends at a newline or semicolon that does not
+// continue an expression, after a `do` that opens a block (and its |parameters|), or before an `end`.
+func (p *parser) collect() []token {
+	var stmt []token
+	depth := 0
+	for p.pos < len(p.tokens) {
+		t := p.tokens[p.pos]
+		switch {
+		case t.kind == tokComment:
+			p.comment(t)
+			p.pos++
+			continue
+		case depth == 0 && len(stmt) > 0 && t.is(tokIdent, "end"):
+			return stmt
+		case depth == 0 && (t.kind == tokNewline || t.is(tokPunct, ";")):
+			if t.kind == tokNewline && len(stmt) > 0 && p.continues(stmt[len(stmt)-1]) {
+				p.pos++
+				continue
+			}
+			return stmt
+		case t.kind == tokPunct:
+			switch t.text {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				if depth > 0 {
+					depth--
+				}
+			}
+		}
+
+		stmt = append(stmt, t)
+		p.pos++
+		if depth == 0 && t.is(tokIdent, "do") {
+			p.blockParams()
+			return stmt
+		}
+		if t.is(tokIdent, "end") {
+			p.modifier()
+			return stmt
+		}
+	}
+	return stmt
+}
+
+// modifier skips a modifier after `end`, e.g. `end unless OS.mac?`, which doesn't open a block.
+func (p *parser) modifier() {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokIdent || !modifierKeywords[p.tokens[p.pos].text] {
+		return
+	}
+	for ; p.pos < len(p.tokens); p.pos++ {
+		if t := p.tokens[p.pos]; t.kind == tokNewline || t.is(tokPunct, ";") {
+			return
+		}
+		if t := p.tokens[p.pos]; t.kind == tokComment {
+			p.comment(t)
+		}
+	}
+}
+
+func (p *parser) continues(last token) bool {
+	if last.kind == tokLabel {
+		return true
+	}
+	if last.kind == tokPunct && continuations[last.text] {
+		return true
+	}
+	// A following line starting with .method continues the chain:
+	for i := p.pos + 1; i < len(p.tokens); i++ {
+		switch next := p.tokens[i]; next.kind {
+		case tokNewline, tokComment:
+			continue
+		case tokPunct:
+			return next.text == "." || next.text == "&."
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// blockParams skips block parameters, e.g. `do |f|`.
+func (p *parser) blockParams() {
+	if p.pos >= len(p.tokens) || !p.tokens[p.pos].is(tokPunct, "|") {
+		return
+	}
+	for p.pos++; p.pos < len(p.tokens); p.pos++ {
+		t := p.tokens[p.pos]
+		if t.kind == tokNewline {
+			return
+		}
+		if t.is(tokPunct, "|") {
+			p.pos++
+			return
+		}
+	}
+}
+
+func (p *parser) statement(stmt []token) error {
+	if len(stmt) == 0 {
+		return nil
+	}
+	first := stmt[0]
+
+	if first.is(tokIdent, "end") {
+		return p.end(first)
+	}
+	if first.kind == tokIdent && branchKeywords[first.text] {
+		p.scope.Clauses = append(p.scope.Clauses, &Clause{Keyword: first.text, Start: first.start})
+	}
+
+	if first.kind == tokIdent && blockKeywords[first.text] {
+		b := &Block{Kind: first.text, Start: first.start}
+		if first.text == "class" || first.text == "module" || first.text == "def" {
+			if len(stmt) > 1 && stmt[1].kind == tokIdent {
+				b.Name = stmt[1].text
+			}
+		}
+		p.push(b)
+		// `while cond do` uses a single `end`:
+		if last := stmt[len(stmt)-1]; last.is(tokIdent, "do") {
+			return nil
+		}
+	} else if first.kind == tokIdent && len(stmt) >= 2 && stmt[1].is(tokPunct, "=") {
+		if len(stmt) >= 3 && stmt[2].literal() {
+			p.scope.Assignments = append(p.scope.Assignments, &Assignment{
+				Name:  first.text,
+				Value: p.literal(stmt[2]),
+				Start: first.start,
+				End:   stmt[len(stmt)-1].end,
+			})
+		}
+	}
+
+	// Nested `x = if cond` or `foo(case x ...` expressions are closed by their own `end`:
+	for i := 1; i < len(stmt); i++ {
+		t := stmt[i]
+		if t.kind != tokIdent || !blockKeywords[t.text] || t.text == "class" || t.text == "def" || t.text == "module" {
+			continue
+		}
+		if prev := stmt[i-1]; prev.kind == tokPunct && prev.text != ")" && prev.text != "]" && prev.text != "}" {
+			p.push(&Block{Kind: t.text, Start: t.start})
+		}
+	}
+
+	var s *Stanza
+	if first.kind == tokIdent && !blockKeywords[first.text] && !rubyKeywords[first.text] && !clauseKeywords[first.text] {
+		s = p.stanza(stmt)
+		p.scope.Stanzas = append(p.scope.Stanzas, s)
+	}
+
+	last := stmt[len(stmt)-1]
+	switch {
+	case last.is(tokIdent, "do"):
+		b := &Block{Start: first.start, Stanza: s}
+		if s != nil {
+			b.Kind = s.Method
+			if arg, ok := s.Arg(); ok && !arg.Symbol {
+				b.Name = arg.Value
+			}
+		}
+		p.push(b)
+	case last.is(tokIdent, "end") && len(stmt) > 1:
+		return p.end(last)
+	}
+	return nil
+}
+
+func (p *parser) push(b *Block) {
+	b.Parent = p.scope
+	p.scope.Blocks = append(p.scope.Blocks, b)
+	p.scope = b
+}
+
+func (p *parser) end(t token) error {
+	if p.scope == p.root {
+		return fmt.Errorf("line %d: unexpected end", t.line)
+	}
+	p.scope.End = t.end
+	p.scope = p.scope.Parent
+	return nil
+}
+
+// stanza interprets a statement as a method call: `method arg, arg, key: value`.
+func (p *parser) stanza(stmt []token) *Stanza {
+	first := stmt[0]
+	s := &Stanza{
+		Method:  first.text,
+		Options: map[string]Literal{},
+		Start:   first.start,
+		Line:    first.line,
+	}
+
+	args := stmt[1:]
+	if n := len(args); n > 0 && args[n-1].is(tokIdent, "do") {
+		args = args[:n-1]
+	}
+	if len(args) > 0 {
+		s.End = args[len(args)-1].end
+	} else {
+		s.End = first.end
+	}
+
+	// url("...") vs url "..."
+	argDepth := 0
+	if len(args) > 0 && args[0].is(tokPunct, "(") && !args[0].space {
+		argDepth = 1
+	}
+
+	depth := 0
+	for i := 0; i < len(args); i++ {
+		t := args[i]
+		if t.kind == tokPunct {
+			switch t.text {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				depth--
+			}
+			continue
+		}
+		if depth != argDepth {
+			continue
+		}
+
+		switch {
+		case t.kind == tokLabel:
+			if i+1 < len(args) && args[i+1].literal() && argEnds(args, i+2) {
+				s.Options[t.text] = p.literal(args[i+1])
+				i++
+			}
+		case t.literal() && i+2 < len(args) && args[i+1].is(tokPunct, "=>") && args[i+2].literal():
+			// Legacy hash syntax, e.g. bottle's `sha256 "..." => :mojave`
+			if argEnds(args, i+3) {
+				s.Options[args[i+2].text] = p.literal(t)
+			}
+			i += 2
+		case t.literal() && argEnds(args, i+1):
+			s.Args = append(s.Args, p.literal(t))
+		}
+	}
+	return s
+}
+
+// argEnds returns true if the argument list token at i terminates an argument.
+func argEnds(args []token, i int) bool {
+	if i >= len(args) {
+		return true
+	}
+	t := args[i]
+	return t.kind == tokPunct && (t.text == "," || t.text == ")")
+}
+
+func (p *parser) literal(t token) Literal {
+	l := Literal{
+		Value:  p.src[t.valStart:t.valEnd],
+		Symbol: t.kind == tokSymbol,
+		Regexp: t.kind == tokRegexp,
+		Flags:  t.flags,
+		Start:  t.valStart,
+		End:    t.valEnd,
+	}
+	return l
+}
+
+// edit replaces the source between Start and End with Text.
+type edit struct {
+	Start, End int
+	Text       string
+}
+
+// lineEdit removes whole lines spanning from start to end, including a trailing blank line.
+func lineEdit(src string, start, end int) edit {
+	for start > 0 && src[start-1] != '\n' {
+		start--
+	}
+	if nl := strings.IndexByte(src[end:], '\n'); nl >= 0 {
+		end += nl + 1
+	} else {
+		end = len(src)
+	}
+	if start > 0 && (start == 1 || src[start-2] == '\n') && strings.HasPrefix(src[end:], "\n") {
+		end++
+	}
+	return edit{Start: start, End: end}
 }
 
-func parseFormulaHashes(formula string) (sums []string) {
-	for _, m := range shasumRe.FindAllStringSubmatch(formula, -1) {
-		sums = append(sums, m[2])
+// applyEdits applies non-overlapping edits to the source.
+func applyEdits(src string, edits []edit) string {
+	sorted := make([]edit, len(edits))
+	copy(sorted, edits)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start > sorted[j].Start })
+	for _, e := range sorted {
+		src = src[:e.Start] + e.Text + src[e.End:]
 	}
-	return
+	return src
 }
@@ -0,0 +1,286 @@
+package brew
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thepwagner/action-update/updater"
+)
+
+const parserFormula = `# url "https://example.com/commented-1.0.0.tar.gz"
+class Foo < Formula
+  desc "Does foo; do not end"
+  homepage "https://example.com/foo"
+  url "https://example.com/foo-1.2.3.tar.gz", using: :homebrew_curl
+  mirror 'https://mirror.example.com/foo-1.2.3.tar.gz'
+  sha256 "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+  version "1.2.3"
+
+  livecheck do
+    url :stable
+    regex(/href=.*?foo[._-]v?(\d+(?:\.\d+)+)\.t/i)
+  end
+
+  resource "bar" do
+    url "https://example.com/bar-4.5.6.tar.gz"
+    sha256 "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+  end
+
+  def install
+    (buildpath/"foo.conf").write <<~EOS
+      url "https://example.com/heredoc-9.9.9.tar.gz"
+      end
+    EOS
+    args = %W[--prefix=#{prefix} --with-#{"bar"}]
+    system "./configure", *args if OS.mac?
+    resources.each { |r| r.stage { system "make" } }
+  end
+end
+__END__
+diff --git a/foo b/foo
+end
+`
+
+func TestParseFormula(t *testing.T) {
+	f, err := parseFormula(parserFormula)
+	require.NoError(t, err)
+
+	main := f.Main()
+	assert.Equal(t, "class", main.Kind)
+	assert.Equal(t, "Foo", main.Name)
+	require.Len(t, main.Comments, 0)
+	require.Len(t, f.Root.Comments, 1)
+
+	urls := main.Find("url")
+	require.Len(t, urls, 1)
+	u, ok := urls[0].Arg()
+	require.True(t, ok)
+	assert.Equal(t, "https://example.com/foo-1.2.3.tar.gz", u.Value)
+	assert.Equal(t, u.Value, parserFormula[u.Start:u.End])
+	assert.Equal(t, "homebrew_curl", urls[0].Options["using"].Value)
+	assert.True(t, urls[0].Options["using"].Symbol)
+
+	mirror, ok := main.First("mirror").Arg()
+	require.True(t, ok)
+	assert.Equal(t, "https://mirror.example.com/foo-1.2.3.tar.gz", parserFormula[mirror.Start:mirror.End])
+
+	version, ok := main.First("version").Arg()
+	require.True(t, ok)
+	assert.Equal(t, "1.2.3", version.Value)
+
+	livecheck := main.Children("livecheck")
+	require.Len(t, livecheck, 1)
+	regex, ok := livecheck[0].First("regex").Arg()
+	require.True(t, ok)
+	assert.True(t, regex.Regexp)
+	assert.Equal(t, "i", regex.Flags)
+	assert.Equal(t, `href=.*?foo[._-]v?(\d+(?:\.\d+)+)\.t`, regex.Value)
+
+	resources := main.Children("resource")
+	require.Len(t, resources, 1)
+	assert.Equal(t, "bar", resources[0].Name)
+	resURL, ok := resources[0].First("url").Arg()
+	require.True(t, ok)
+	assert.Equal(t, "https://example.com/bar-4.5.6.tar.gz", resURL.Value)
+	assert.Equal(t, "end", parserFormula[resources[0].End-3:resources[0].End])
+
+	install := main.Children("def")
+	require.Len(t, install, 1)
+	assert.Equal(t, "install", install[0].Name)
+	assert.Empty(t, install[0].Find("url"))
+}
+
+func TestParseFormula_Errors(t *testing.T) {
+	cases := map[string]string{
+		"unterminated string": "class Foo < Formula\n  url \"https://example.com\n",
+		"unterminated block":  "class Foo < Formula\n  resource \"x\" do\n  end\n",
+		"unexpected end":      "class Foo < Formula\nend\nend\n",
+		"unterminated doc":    "class Foo < Formula\n  x = <<~EOS\n  foo\nend\n",
+	}
+	for label, src := range cases {
+		t.Run(label, func(t *testing.T) {
+			_, err := parseFormula(src)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseFormula_Expressions(t *testing.T) {
+	cases := map[string]string{
+		"begin end modifier":  "begin\n    x\n  end unless y",
+		"if end modifier":     "if a\n    b\n  end unless c",
+		"end while modifier":  "begin\n    x\n  end while y # retry",
+		"shift constant":      "x = 1<<FOO",
+		"division":            "a /2",
+		"division with space": "a / 2",
+		"regexp argument":     "regex /foo/",
+		"heredoc argument":    "x = <<FOO\n  foo\nFOO",
+	}
+	for label, expr := range cases {
+		t.Run(label, func(t *testing.T) {
+			f, err := parseFormula("class Foo < Formula\n  " + expr + "\n  url \"https://example.com/foo-1.2.3.tar.gz\"\nend\n")
+			require.NoError(t, err)
+			main := f.Main()
+			assert.Equal(t, "Foo", main.Name)
+			u, ok := main.First("url").Arg()
+			require.True(t, ok)
+			assert.Equal(t, "https://example.com/foo-1.2.3.tar.gz", u.Value)
+		})
+	}
+}
+
+func TestParseFormula_Branches(t *testing.T) {
+	f, err := parseFormula(`class Foo < Formula
+  if Hardware::CPU.arm?
+    url "https://example.com/foo-arm64-1.2.3.tar.gz"
+  elsif Hardware::CPU.intel?
+    url "https://example.com/foo-amd64-1.2.3.tar.gz"
+  else
+    odie "unsupported"
+  end
+end
+`)
+	require.NoError(t, err)
+	conditional := f.Main().Children("if")
+	require.Len(t, conditional, 1)
+
+	var kinds, urls []string
+	for _, branch := range conditional[0].Branches() {
+		kinds = append(kinds, branch.Kind)
+		if u, ok := branch.First("url").Arg(); ok {
+			urls = append(urls, u.Value)
+		}
+	}
+	assert.Equal(t, []string{"if", "elsif", "else"}, kinds)
+	assert.Equal(t, []string{"https://example.com/foo-arm64-1.2.3.tar.gz", "https://example.com/foo-amd64-1.2.3.tar.gz"}, urls)
+}
+
+func TestParseFormulaDeps(t *testing.T) {
+	deps, err := parseFormulaDeps(parserFormula)
+	require.NoError(t, err)
+	assert.Equal(t, []updater.Dependency{
+		{Path: "https://example.com/foo-1.2.3.tar.gz", Version: "1.2.3"},
+		{Path: "https://example.com/bar-4.5.6.tar.gz", Version: "4.5.6"},
+	}, deps)
+}
+
+func TestExpandVersion(t *testing.T) {
+	cases := []struct {
+		template string
+		version  string
+		expected string
+	}{
+		{template: "https://example.com/foo-#{version}.tar.gz", version: "1.2.3", expected: "https://example.com/foo-1.2.3.tar.gz"},
+		{template: "https://example.com/foo-#{VERSION}.tar.gz", version: "1.2.3", expected: "https://example.com/foo-1.2.3.tar.gz"},
+		{template: "https://example.com/#{version.csv.second}/Foo-#{version.csv.first}.dmg", version: "1.2.3,4567", expected: "https://example.com/4567/Foo-1.2.3.dmg"},
+		{template: "https://example.com/#{version.before_comma}/#{version.after_comma}", version: "1.2.3,4567", expected: "https://example.com/1.2.3/4567"},
+		{template: "https://example.com/#{version.major_minor}/foo-#{version.no_dots}.zip", version: "1.2.3", expected: "https://example.com/1.2/foo-123.zip"},
+		{template: "https://example.com/#{version.major}.x/#{version.dots_to_underscores}", version: "1.2.3", expected: "https://example.com/1.x/1_2_3"},
+		{template: "https://example.com/#{version.unknown}/#{name}", version: "1.2.3", expected: "https://example.com/#{version.unknown}/#{name}"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.expected, func(t *testing.T) {
+			assert.Equal(t, tc.expected, expandVersion(tc.template, tc.version))
+		})
+	}
+}
+
+func TestParseDirectives(t *testing.T) {
+	f, err := parseFormula(`# update: source=github:foo/foo tag-regex=^release-(.*)$
+class Foo < Formula
+  # update: pin 1.x
+  url "https://example.com/foo-1.2.3.tar.gz"
+
+  resource "bar" do
+    # update: ignore
+    url "https://example.com/bar-4.5.6.tar.gz"
+  end
+
+  resource "baz" do
+    # update: scheme=numeric
+    url "https://example.com/baz-7.8.tar.gz"
+  end
+end
+`)
+	require.NoError(t, err)
+	deps := formulaDeps(f)
+	require.Len(t, deps, 3)
+	for _, dep := range deps {
+		assert.NoError(t, dep.err)
+	}
+
+	foo := deps[0].directives
+	assert.Equal(t, "github:foo/foo", foo.source)
+	assert.Equal(t, "1.x", foo.pin)
+	assert.Equal(t, []string{"1.3.0", "v1.4"}, foo.allowed([]string{"1.3.0", "2.0.0", "v1.4"}))
+	assert.False(t, foo.ignore)
+
+	assert.True(t, deps[1].directives.ignore)
+	assert.Equal(t, NumericScheme, deps[2].directives.scheme)
+}
+
+func TestParseDirectives_Errors(t *testing.T) {
+	cases := map[string]string{
+		"empty":               "# update:",
+		"unknown":             "# update: frobnicate",
+		"pin without value":   "# update: pin",
+		"ignore with value":   "# update: ignore=true",
+		"unknown source":      "# update: source=sourceforge:foo",
+		"incomplete source":   "# update: source=github:foo",
+		"invalid regex":       "# update: tag-regex=v(",
+		"regex without group": "# update: tag-regex=v.*",
+		"unknown scheme":      "# update: scheme=roman",
+		"unknown channel":     "# update: channel=nightly",
+		"unknown line":        "# update: release-line=minor",
+	}
+	for label, comment := range cases {
+		t.Run(label, func(t *testing.T) {
+			f, err := parseFormula(comment + "\nclass Foo < Formula\n  url \"https://example.com/foo-1.2.3.tar.gz\"\nend\n")
+			require.NoError(t, err)
+			deps := formulaDeps(f)
+			require.Len(t, deps, 1)
+			assert.Equal(t, "1.2.3", deps[0].Version)
+			assert.Error(t, deps[0].err)
+		})
+	}
+}
+
+func TestUpdater_InvalidDirective(t *testing.T) {
+	srv := newFakeServer(t, map[string]string{
+		"/foo-1.0.0.tar.gz": "foo 1.0.0",
+		"/foo-1.1.0.tar.gz": "foo 1.1.0",
+	})
+	f := newFormulaFixture(t, fmt.Sprintf("class Foo < Formula\n  url \"%s/foo-1.0.0.tar.gz\"\n  sha256 \"%s\"\nend\n", srv.URL, sha256Hex("foo 1.0.0")))
+	bar := "# update: please keep on 1.x\nclass Bar < Formula\n  url \"https://example.com/bar-2.0.0.tar.gz\"\nend\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(filepath.Dir(f.path), "bar.rb"), []byte(bar), 0600))
+
+	deps := f.deps(2)
+	assert.Equal(t, []updater.Dependency{
+		{Path: "https://example.com/bar-2.0.0.tar.gz", Version: "2.0.0"},
+		{Path: srv.URL + "/foo-1.0.0.tar.gz", Version: "1.0.0"},
+	}, deps)
+
+	_, err := f.Check(context.Background(), deps[0], nil)
+	assert.EqualError(t, err, `line 1: invalid update directive "please": unknown directive "please"`)
+
+	formula := f.apply(&updater.Update{Path: srv.URL + "/foo-1.0.0.tar.gz", Previous: "1.0.0", Next: "1.1.0"})
+	assert.Contains(t, formula, sha256Hex("foo 1.1.0"))
+}
+
+func TestUpdater_UnparseableFormula(t *testing.T) {
+	srv := newFakeServer(t, map[string]string{
+		"/foo-1.0.0.tar.gz": "foo 1.0.0",
+		"/foo-1.1.0.tar.gz": "foo 1.1.0",
+	})
+	f := newFormulaFixture(t, fmt.Sprintf("class Foo < Formula\n  url \"%s/foo-1.0.0.tar.gz\"\n  sha256 \"%s\"\nend\n", srv.URL, sha256Hex("foo 1.0.0")))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(filepath.Dir(f.path), "broken.rb"), []byte("class Broken < Formula\n  url \"https://example.com\n"), 0600))
+
+	assert.Equal(t, []updater.Dependency{{Path: srv.URL + "/foo-1.0.0.tar.gz", Version: "1.0.0"}}, f.deps(1))
+	formula := f.apply(&updater.Update{Path: srv.URL + "/foo-1.0.0.tar.gz", Previous: "1.0.0", Next: "1.1.0"})
+	assert.Contains(t, formula, sha256Hex("foo 1.1.0"))
+}
@@ -0,0 +1,75 @@
+package brew
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/thepwagner/action-update/updater"
+)
+
+// lsRemoteTags lists the tags of a git repository, mapping each tag to the commit it points to.
+func lsRemoteTags(ctx context.Context, remote string) (map[string]string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--tags", remote)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing tags of %s: %w: %s", remote, err, strings.TrimSpace(stderr.String()))
+	}
+
+	tags := map[string]string{}
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		split := strings.SplitN(s.Text(), "\t", 2)
+		if len(split) != 2 || !strings.HasPrefix(split[1], "refs/tags/") {
+			continue
+		}
+		commit, tag := split[0], strings.TrimPrefix(split[1], "refs/tags/")
+
+		// Annotated tags are listed twice, prefer the peeled commit (v1.2.3^{}) over the tag object:
+		if peeled := strings.TrimSuffix(tag, "^{}"); peeled != tag {
+			tags[peeled] = commit
+		} else if _, ok := tags[tag]; !ok {
+			tags[tag] = commit
+		}
+	}
+	return tags, s.Err()
+}
+
+// listGitTagVersions lists the versions of a git dependency's tags, e.g. those sharing the current tag's prefix.
+func listGitTagVersions(ctx context.Context, dep *formulaDep) ([]string, error) {
+	tags, err := lsRemoteTags(ctx, dep.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(tags))
+	for tag := range tags {
+		if version, ok := dep.tagVersion(tag); ok {
+			versions = append(versions, version)
+		}
+	}
+	return versions, nil
+}
+
+// revisionEdits updates the commit a git dependency's tag is expected to resolve to.
+func revisionEdits(ctx context.Context, dep *formulaDep, update updater.Update) ([]edit, error) {
+	if dep.revision == nil {
+		return nil, nil
+	}
+	tags, err := lsRemoteTags(ctx, dep.Path)
+	if err != nil {
+		return nil, err
+	}
+	next := nextVersion(update)
+	for tag, commit := range tags {
+		if version, ok := dep.tagVersion(tag); ok && version == next {
+			return []edit{{Start: dep.revision.Start, End: dep.revision.End, Text: commit}}, nil
+		}
+	}
+	return nil, fmt.Errorf("tag for version %q not found in %s", next, dep.Path)
+}
@@ -0,0 +1,67 @@
+package brew
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thepwagner/action-update/updater"
+)
+
+// gitRepo creates a repository with an annotated tag per commit, returning its path and the commit of each tag.
+func gitRepo(t *testing.T, tags ...string) (string, map[string]string) {
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+
+	git("init", "-q")
+	commits := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		git("commit", "-q", "--allow-empty", "-m", tag)
+		git("tag", "-a", "-m", tag, tag)
+		commits[tag] = git("rev-parse", "HEAD")
+	}
+	return dir, commits
+}
+
+const gitFormula = `class Foo < Formula
+  url "%s", tag: "release-%s", revision: "%s"
+  license "MIT"
+
+  resource "bar" do
+    url "%s", tag: "v%s", revision: "%s"
+  end
+end
+`
+
+func TestUpdater_Dependencies_Git(t *testing.T) {
+	deps, err := parseFormulaDeps(fmt.Sprintf(gitFormula, "https://github.com/foo/foo.git", "1.2.3", "abc", "https://github.com/foo/bar.git", "4.5.6", "def"))
+	require.NoError(t, err)
+	assert.Equal(t, []updater.Dependency{
+		{Path: "https://github.com/foo/foo.git", Version: "1.2.3"},
+		{Path: "https://github.com/foo/bar.git", Version: "4.5.6"},
+	}, deps)
+}
+
+func TestUpdater_Update_Git(t *testing.T) {
+	foo, fooCommits := gitRepo(t, "release-1.0.0", "v9.0.0", "release-1.1.0")
+	bar, barCommits := gitRepo(t, "v2.0.0", "v2.1.0")
+
+	f := newFormulaFixture(t, fmt.Sprintf(gitFormula, foo, "1.0.0", fooCommits["release-1.0.0"], bar, "2.0.0", barCommits["v2.0.0"]))
+	for _, dep := range f.deps(2) {
+		update, err := f.Check(context.Background(), dep, nil)
+		require.NoError(t, err)
+		f.apply(update)
+	}
+
+	expected := fmt.Sprintf(gitFormula, foo, "1.1.0", fooCommits["release-1.1.0"], bar, "2.1.0", barCommits["v2.1.0"])
+	assert.Equal(t, expected, f.read())
+}
@@ -12,178 +12,385 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"sort"
+	"regexp"
 	"strings"
 
 	"github.com/google/go-github/v33/github"
 	"github.com/sirupsen/logrus"
 	"github.com/thepwagner/action-update/updater"
-	"golang.org/x/mod/semver"
 )
 
-func checkGitHubRelease(ctx context.Context, repos *github.RepositoriesService, dep updater.Dependency) (*updater.Update, error) {
-	releases, err := listGitHubReleases(ctx, repos, dep)
+// listGitHubReleases lists a repository's releases on the channel, newest first. Drafts are never listed.
+// If current is provided, pages are fetched until it matches a tag. Repositories without releases fall back to
+// listing their tags, as releases without a publication date.
+func listGitHubReleases(ctx context.Context, repos *github.RepositoriesService, owner, name string, ch Channel, current func(tag string) bool) ([]taggedRelease, error) {
+	log := logrus.WithFields(logrus.Fields{
+		"owner": owner,
+		"repo":  name,
+	})
+
+	var ret []taggedRelease
+	var listed int
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		releases, res, err := repos.ListReleases(ctx, owner, name, opts)
+		if err != nil {
+			return nil, fmt.Errorf("querying for releases: %w", err)
+		}
+		listed += len(releases)
+		found := false
+		for _, release := range releases {
+			tag := release.GetTagName()
+			found = found || (current != nil && current(tag))
+			if release.GetDraft() || releaseChannel(tag, release.GetPrerelease()) > ch {
+				log.WithField("tag", tag).Debug("skipping release outside channel")
+				continue
+			}
+			ret = append(ret, taggedRelease{tag: tag, published: release.GetPublishedAt().Time})
+		}
+		if found || res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+	log.WithField("releases", listed).Debug("fetched releases")
+	if listed > 0 {
+		return ret, nil
+	}
+
+	tags, err := listGitHubTags(ctx, repos, owner, name)
 	if err != nil {
-		return nil, fmt.Errorf("listing releases: %w", err)
+		return nil, err
+	}
+	log.WithField("tags", len(tags)).Debug("no releases found, fetched tags")
+	for _, tag := range tags {
+		if ch.allows(tag.GetName()) {
+			ret = append(ret, taggedRelease{tag: tag.GetName()})
+		}
 	}
+	return ret, nil
+}
 
-	depVer := semverIsh(dep.Version)
-	for _, release := range releases {
-		switch semver.Compare(depVer, release) {
-		case -1:
-			return &updater.Update{
-				Path:     dep.Path,
-				Previous: dep.Version,
-				Next:     release,
-			}, nil
-		case 1:
-			return nil, nil
+func listGitHubTags(ctx context.Context, repos *github.RepositoriesService, owner, name string) ([]*github.RepositoryTag, error) {
+	var ret []*github.RepositoryTag
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		tags, res, err := repos.ListTags(ctx, owner, name, opts)
+		if err != nil {
+			return nil, fmt.Errorf("querying for tags: %w", err)
+		}
+		ret = append(ret, tags...)
+		if res.NextPage == 0 {
+			return ret, nil
 		}
+		opts.Page = res.NextPage
 	}
-	return nil, nil
 }
 
-func listGitHubReleases(ctx context.Context, repos *github.RepositoriesService, dependency updater.Dependency) ([]string, error) {
-	owner, name := parseGitHubRelease(dependency.Path)
-	releases, _, err := repos.ListReleases(ctx, owner, name, &github.ListOptions{PerPage: 100})
+// parseGitHubRelease returns the repository of a github.com url, like https://github.com/owner/repo/releases/...
+func parseGitHubRelease(rawURL string) (owner, repoName string, err error) {
+	parsed, err := url.Parse(rawURL)
 	if err != nil {
-		return nil, fmt.Errorf("querying for releases: %w", err)
+		return "", "", err
 	}
-	log := logrus.WithFields(logrus.Fields{
-		"owner": owner,
-		"repo":  name,
-	})
-	log.WithField("releases", len(releases)).Debug("fetched releases")
+	pathSplit := strings.SplitN(parsed.Path, "/", 4)
+	if parsed.Host != "github.com" || len(pathSplit) < 3 || pathSplit[1] == "" || pathSplit[2] == "" {
+		return "", "", fmt.Errorf("%s is not a github.com repository url", rawURL)
+	}
+	return pathSplit[1], pathSplit[2], nil
+}
+
+// releaseUpdate is an update between two GitHub releases, identified by tag.
+type releaseUpdate struct {
+	updater.Update
+	previousTag, nextTag string
+}
 
-	ret := make([]string, 0, len(releases))
-	for _, release := range releases {
-		ret = append(ret, release.GetTagName())
+// assetURL returns the URL of an asset in the next release, from the URL of the asset in the previous release.
+func (r releaseUpdate) assetURL(oldURL string) string {
+	prev, next := strings.TrimPrefix(r.Previous, "v"), strings.TrimPrefix(r.Next, "v")
+	// Replace the tag first, then the version in filenames. Asset names may separate versions like the tag does:
+	replacements := []string{
+		"/" + url.PathEscape(r.previousTag) + "/", "/" + url.PathEscape(r.nextTag) + "/",
+		"/" + r.previousTag + "/", "/" + r.nextTag + "/",
+		"/" + r.previousTag + ".", "/" + r.nextTag + ".",
+		prev, next,
 	}
+	if strings.HasSuffix(oldURL, "/"+r.previousTag) {
+		// e.g. https://api.github.com/repos/o/r/tarball/v1.2.3
+		oldURL = strings.TrimSuffix(oldURL, r.previousTag) + r.nextTag
+	}
+	for _, sep := range versionSeparators {
+		if p := strings.ReplaceAll(prev, ".", sep); p != prev {
+			replacements = append(replacements, p, strings.ReplaceAll(next, ".", sep))
+		}
+	}
+	return strings.NewReplacer(replacements...).Replace(oldURL)
+}
 
-	sort.SliceStable(ret, func(i, j int) bool {
-		return semver.Compare(ret[i], ret[j]) > 0
-	})
-	return ret, nil
+// githubSource discovers the releases of GitHub repositories, or their tags if they don't publish releases.
+type githubSource struct{ u *Updater }
+
+func (s githubSource) Match(dep updater.Dependency) bool {
+	_, _, err := parseGitHubRelease(dep.Path)
+	return err == nil
 }
 
-func parseGitHubRelease(path string) (owner, repoName string) {
-	parsed, _ := url.Parse(path)
-	pathSplit := strings.SplitN(parsed.Path, "/", 4)
-	return pathSplit[1], pathSplit[2]
+func (s githubSource) ListVersions(ctx context.Context, q VersionQuery) ([]SourceVersion, error) {
+	fd := q.formulaDep()
+	owner, name, err := parseGitHubRelease(q.Path)
+	if err != nil {
+		return nil, err
+	}
+	releases, err := listGitHubReleases(ctx, s.u.ghRepos, owner, name, q.Channel, fd.currentRelease)
+	if err != nil {
+		return nil, err
+	}
+	dates := versionDates{}
+	versions := fd.releaseVersions(releases, dates)
+	return sourceVersions(versions, dates), nil
+}
+
+func (s githubSource) ResolveHash(ctx context.Context, update updater.Update, oldHash string) (string, error) {
+	return updatedGitHubHash(ctx, s.u.client, s.u.ghRepos, update, oldHash)
+}
+
+func (s githubSource) resolveHashes(ctx context.Context, dep *formulaDep, update updater.Update, oldHashes []string) (map[string]string, error) {
+	ru, err := s.u.releaseUpdate(ctx, dep, update)
+	if err != nil {
+		return nil, err
+	}
+	return updatedGitHubHashes(ctx, s.u.client, s.u.ghRepos, ru, oldHashes)
 }
 
 func updatedGitHubHash(ctx context.Context, client *http.Client, repos *github.RepositoriesService, update updater.Update, oldHash string) (string, error) {
-	// Fetch the previous release:
-	owner, repoName := parseGitHubRelease(update.Path)
-	prevRelease, err := getReleaseByTag(ctx, repos, owner, repoName, update.Previous)
+	ru := releaseUpdate{Update: update, previousTag: update.Previous, nextTag: update.Next}
+	newHashes, err := updatedGitHubHashes(ctx, client, repos, ru, []string{oldHash})
 	if err != nil {
 		return "", err
 	}
+	return newHashes[oldHash], nil
+}
+
+// updatedGitHubHashes resolves several hashes (e.g. one per platform) from a single pass over the previous release.
+// The returned map is keyed by previous hash, and omits hashes that were not found.
+func updatedGitHubHashes(ctx context.Context, client *http.Client, repos *github.RepositoriesService, update releaseUpdate, oldHashes []string) (map[string]string, error) {
+	// Fetch the previous release:
+	owner, repoName, err := parseGitHubRelease(update.Path)
+	if err != nil {
+		return nil, err
+	}
+	prevRelease, err := getReleaseByTag(ctx, repos, owner, repoName, update.previousTag)
+	if err != nil {
+		return nil, err
+	}
+	return updatedReleaseHashes(ctx, client, githubReleaseFiles(prevRelease), update, oldHashes)
+}
+
+// releaseFiles are the downloads of a release, from any forge.
+type releaseFiles struct {
+	// assets are attached to the release, and may include checksum files.
+	assets []releaseAsset
+	// sources are archives of the tagged source code.
+	sources []string
+}
+
+type releaseAsset struct {
+	name string
+	url  string
+	// size is 0 if unknown.
+	size int
+}
+
+func githubReleaseFiles(release *github.RepositoryRelease) releaseFiles {
+	files := releaseFiles{sources: sourceURLs(release)}
+	for _, a := range release.Assets {
+		files.assets = append(files.assets, releaseAsset{name: a.GetName(), url: a.GetBrowserDownloadURL(), size: a.GetSize()})
+	}
+	return files
+}
+
+// updatedReleaseHashes searches the files of the previous release for the old hashes: in checksum files, then assets,
+// then source archives. The returned map is keyed by previous hash, and omits hashes that were not found.
+func updatedReleaseHashes(ctx context.Context, client *http.Client, prevRelease releaseFiles, update releaseUpdate, oldHashes []string) (map[string]string, error) {
+
+	newHashes := make(map[string]string, len(oldHashes))
+	pending := func() []string {
+		var ret []string
+		for _, h := range oldHashes {
+			if _, ok := newHashes[h]; !ok {
+				ret = append(ret, h)
+			}
+		}
+		return ret
+	}
 
 	// First pass, does the project release a SHASUMS etc file we can grab?
-	for _, prevAsset := range prevRelease.Assets {
-		log := logrus.WithField("name", prevAsset.GetName())
-		oldAsset, err := isShasumAsset(ctx, client, prevAsset, oldHash)
-		if err != nil {
-			log.WithError(err).Warn("inspecting potential hash asset")
-			continue
-		} else if len(oldAsset) == 0 {
-			log.Debug("old shasum asset not found")
-			continue
+	for _, prevAsset := range prevRelease.assets {
+		remaining := pending()
+		if len(remaining) == 0 {
+			return newHashes, nil
 		}
-		log.Debug("identified shasum asset in previous release")
 
-		// The previous release contained a shasum file that contained the previous hash
-		// Does the new release have the same file?
-		newHash, err := updatedHashFromShasumAsset(ctx, client, prevAsset, oldAsset, oldHash, update)
+		log := logrus.WithField("name", prevAsset.name)
+		oldContents, err := fetchShasumAsset(ctx, client, prevAsset)
 		if err != nil {
-			log.WithError(err).Warn("fetching updated hash asset")
+			log.WithError(err).Warn("inspecting potential hash asset")
 			continue
 		}
-		if newHash != "" {
-			log.Debug("fetched corresponding shasum asset from new release")
-			return newHash, nil
+
+		var newContents []string
+		for _, oldHash := range remaining {
+			if !shasumsContain(oldContents, oldHash) {
+				continue
+			}
+			log.Debug("identified shasum asset in previous release")
+
+			// The previous release contained a shasum file that contained the previous hash
+			// Does the new release have the same file?
+			if newContents == nil {
+				newContents, err = updatedShasumAsset(ctx, client, prevAsset, update)
+				if err != nil {
+					log.WithError(err).Warn("fetching updated hash asset")
+					break
+				}
+			}
+			if newHash := hashFromShasums(oldContents, newContents, oldHash); newHash != "" {
+				log.Debug("fetched corresponding shasum asset from new release")
+				newHashes[oldHash] = newHash
+			}
 		}
 	}
 
 	// There are no shasum files - get downloading
 	logrus.Debug("shasum file not found, searching files from previous release")
-	for _, prevAsset := range prevRelease.Assets {
-		log := logrus.WithField("name", prevAsset.GetName())
-		h, err := isHashAsset(ctx, client, prevAsset.GetBrowserDownloadURL(), oldHash)
+	for _, prevAsset := range prevRelease.assets {
+		remaining := pending()
+		if len(remaining) == 0 {
+			return newHashes, nil
+		}
+
+		log := logrus.WithField("name", prevAsset.name)
+		sum, err := assetHash(ctx, client, prevAsset.url, remaining[0])
 		if err != nil {
 			log.WithError(err).Warn("checking hash of previous assets")
 			continue
-		} else if !h {
-			continue
 		}
-		log.Debug("identified hashed asset in previous release")
+		for _, oldHash := range remaining {
+			if sum != oldHash {
+				continue
+			}
+			log.Debug("identified hashed asset in previous release")
 
-		// This asset from a previous release matched the previous hash
-		// Does the new release have the same file?
-		newHash, err := updatedHashFromAsset(ctx, client, prevAsset.GetBrowserDownloadURL(), update, oldHash)
-		if err != nil {
-			return "", err
-		}
-		if newHash != "" {
-			log.Debug("fetched corresponding asset from new release")
-			return newHash, nil
+			// This asset from a previous release matched the previous hash
+			// Does the new release have the same file?
+			newHash, err := updatedHashFromAsset(ctx, client, prevAsset.url, update, oldHash)
+			if err != nil {
+				return nil, err
+			}
+			if newHash != "" {
+				log.Debug("fetched corresponding asset from new release")
+				newHashes[oldHash] = newHash
+			}
 		}
 	}
 
 	logrus.Debug("not found in release assets, checking source archives...")
-	for _, sourceURL := range sourceURLs(prevRelease) {
-		ok, err := isHashAsset(ctx, client, sourceURL, oldHash)
-		if err != nil {
-			return "", err
-		}
-		if !ok {
-			continue
+	for _, oldHash := range pending() {
+		for _, sourceURL := range prevRelease.sources {
+			ok, err := isHashAsset(ctx, client, sourceURL, oldHash)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			logrus.WithField("source_url", sourceURL).Debug("found as source archive")
+			newHash, err := updatedHashFromAsset(ctx, client, sourceURL, update, oldHash)
+			if err != nil {
+				return nil, err
+			}
+			if newHash != "" {
+				newHashes[oldHash] = newHash
+			}
+			break
 		}
-		logrus.WithField("source_url", sourceURL).Debug("found as source archive")
-		return updatedHashFromAsset(ctx, client, sourceURL, update, oldHash)
 	}
 
-	return "", nil
+	return newHashes, nil
 }
 
 func sourceURLs(prevRelease *github.RepositoryRelease) []string {
-	archiveByTagRoot := strings.ReplaceAll(prevRelease.GetHTMLURL(), "releases/tag", "archive")
-	return []string{
-		prevRelease.GetTarballURL(),
-		prevRelease.GetZipballURL(),
-		fmt.Sprintf("%s.tar.gz", archiveByTagRoot),
-		fmt.Sprintf("%s.zip", archiveByTagRoot),
+	var ret []string
+	for _, u := range []string{prevRelease.GetTarballURL(), prevRelease.GetZipballURL()} {
+		if u != "" {
+			ret = append(ret, u)
+		}
 	}
+	if htmlURL := prevRelease.GetHTMLURL(); htmlURL != "" {
+		archiveByTagRoot := strings.ReplaceAll(htmlURL, "releases/tag", "archive")
+		ret = append(ret, fmt.Sprintf("%s.tar.gz", archiveByTagRoot), fmt.Sprintf("%s.zip", archiveByTagRoot))
+	}
+	return ret
 }
 
+// getReleaseByTag fetches a release. Tags without a release are returned as a release without assets, so their
+// source archives can still be found.
 func getReleaseByTag(ctx context.Context, repos *github.RepositoriesService, owner, repoName, version string) (*github.RepositoryRelease, error) {
-	release, _, err := repos.GetReleaseByTag(ctx, owner, repoName, version)
-	if err == nil {
-		return release, nil
+	candidates := []string{version}
+	// If 1.2.3 is not found, try v1.2.3
+	if asSemver := semverIsh(version); asSemver != "" && asSemver != version {
+		candidates = append(candidates, asSemver)
 	}
 
-	// If 1.2.3 is not found, try v1.2.3
-	if asSemver := semverIsh(version); asSemver != version {
-		var githubErr *github.ErrorResponse
-		if errors.As(err, &githubErr) && githubErr.Message == "Not Found" {
-			release, _, err := repos.GetReleaseByTag(ctx, owner, repoName, asSemver)
-			if err == nil {
-				return release, nil
-			}
+	var err error
+	for _, tag := range candidates {
+		var release *github.RepositoryRelease
+		release, _, err = repos.GetReleaseByTag(ctx, owner, repoName, tag)
+		if err == nil {
+			return release, nil
+		}
+		if !isNotFound(err) {
+			return nil, err
 		}
 	}
 
+	tags, tagErr := listGitHubTags(ctx, repos, owner, repoName)
+	if tagErr != nil {
+		return nil, tagErr
+	}
+	for _, tag := range tags {
+		for _, candidate := range candidates {
+			if tag.GetName() != candidate {
+				continue
+			}
+			logrus.WithField("tag", candidate).Debug("release not found, using tag")
+			return &github.RepositoryRelease{
+				TagName:    tag.Name,
+				HTMLURL:    github.String(fmt.Sprintf("https://github.com/%s/%s/releases/tag/%s", owner, repoName, candidate)),
+				TarballURL: tag.TarballURL,
+				ZipballURL: tag.ZipballURL,
+			}, nil
+		}
+	}
 	return nil, err
 }
 
-// isShasumAsset returns true if the release asset is a SHASUMS file containing the previous hash
-func isShasumAsset(ctx context.Context, client *http.Client, asset *github.ReleaseAsset, oldHash string) ([]string, error) {
-	if asset.GetSize() > 1024 {
+func isNotFound(err error) bool {
+	var githubErr *github.ErrorResponse
+	return errors.As(err, &githubErr) && githubErr.Message == "Not Found"
+}
+
+// checksumNameRe matches the names of assets that are likely checksum files, for assets of unknown size.
+var checksumNameRe = regexp.MustCompile(`(?i)(sha\d*sums?|checksums?)(\.txt)?$|\.sha(1|256|512)(sum)?$`)
+
+// fetchShasumAsset returns the lines of a release asset that may be a SHASUMS file.
+func fetchShasumAsset(ctx context.Context, client *http.Client, asset releaseAsset) ([]string, error) {
+	if asset.size > 1024 || (asset.size == 0 && !checksumNameRe.MatchString(asset.name)) {
 		return nil, nil
 	}
 
-	req, err := http.NewRequest("GET", asset.GetBrowserDownloadURL(), nil)
+	req, err := http.NewRequest("GET", asset.url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -194,96 +401,157 @@ func isShasumAsset(ctx context.Context, client *http.Client, asset *github.Relea
 		return nil, err
 	}
 	defer res.Body.Close()
-	b, err := ioutil.ReadAll(res.Body)
+	return readShasums(res.Body)
+}
+
+func updatedShasumAsset(ctx context.Context, client *http.Client, asset releaseAsset, update releaseUpdate) ([]string, error) {
+	res, err := getAsset(ctx, client, update.assetURL(asset.url))
 	if err != nil {
 		return nil, err
 	}
-	s := string(b)
-	if !strings.Contains(s, oldHash) {
-		return nil, nil
-	}
-	return strings.Split(s, "\n"), nil
+	defer res.Body.Close()
+	return readShasums(res.Body)
 }
 
-func updatedHashFromShasumAsset(ctx context.Context, client *http.Client, asset *github.ReleaseAsset, oldContents []string, oldHash string, update updater.Update) (string, error) {
-	res, err := getUpdatedAsset(ctx, client, asset.GetBrowserDownloadURL(), update)
+func readShasums(r io.Reader) ([]string, error) {
+	b, err := ioutil.ReadAll(r)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer res.Body.Close()
-	b, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return "", err
+	var lines []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+func shasumsContain(contents []string, hash string) bool {
+	for _, line := range contents {
+		if strings.Contains(line, hash) {
+			return true
+		}
 	}
-	s := string(b)
+	return false
+}
 
+// hashFromShasums finds the file corresponding the old hash in the old SHASUMS, and returns its hash in the new SHASUMS.
+func hashFromShasums(oldContents, newContents []string, oldHash string) string {
 	// If there's one line, extract the checksum and return it:
-	if len(oldContents) == 1 {
-		return strings.SplitN(s, " ", 2)[0], nil
+	if len(oldContents) == 1 && len(newContents) == 1 {
+		return strings.SplitN(newContents[0], " ", 2)[0]
 	}
 
 	// If there's multiple lines, find the file corresponding the old hash:
 	var hashedFile string
 	for _, oldLine := range oldContents {
 		split := strings.SplitN(oldLine, " ", 2)
-		if split[0] == oldHash {
+		if split[0] == oldHash && len(split) == 2 {
 			hashedFile = split[1]
 		}
 	}
 	if hashedFile == "" {
-		return "", nil
+		return ""
 	}
 
 	logrus.WithField("fn", hashedFile).Debug("identified hashed file in shasum asset")
-	for _, newLine := range strings.Split(s, "\n") {
+	for _, newLine := range newContents {
 		split := strings.SplitN(newLine, " ", 2)
 		if len(split) == 1 {
 			continue
 		}
 		if split[1] == hashedFile {
-			return split[0], nil
+			return split[0]
 		}
 	}
-
-	return "", nil
+	return ""
 }
 
-func getUpdatedAsset(ctx context.Context, client *http.Client, oldURL string, update updater.Update) (*http.Response, error) {
-	newURL := updatedURL(oldURL, update)
+// getAsset fetches a URL, failing unless the response is successful.
+func getAsset(ctx context.Context, client *http.Client, newURL string) (*http.Response, error) {
 	req, err := http.NewRequest("GET", newURL, nil)
 	if err != nil {
 		return nil, err
 	}
 	req = req.WithContext(ctx)
-	return client.Do(req)
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("fetching %s: %s", newURL, res.Status)
+	}
+	return res, nil
+}
+
+// fetchOptional fetches a small file, returning nil if it doesn't exist.
+func fetchOptional(ctx context.Context, client *http.Client, rawURL string) ([]byte, error) {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		return ioutil.ReadAll(res.Body)
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("fetching %s: %s", rawURL, res.Status)
+	}
 }
 
 func updatedURL(oldURL string, update updater.Update) string {
-	newURL := strings.ReplaceAll(oldURL, update.Previous, update.Next)
-	newURL = strings.ReplaceAll(newURL, update.Previous[1:], update.Next[1:])
-	return newURL
+	// Tags may or may not carry a "v" prefix, compare the bare versions:
+	return strings.ReplaceAll(oldURL, strings.TrimPrefix(update.Previous, "v"), strings.TrimPrefix(update.Next, "v"))
+}
+
+// updatedTemplateURL returns the URL of the next version of an artifact, from a URL that may be a template.
+func updatedTemplateURL(template string, update updater.Update) string {
+	if interpolationRe.MatchString(template) {
+		return expandVersion(template, nextVersion(update))
+	}
+	return updatedURL(template, update)
 }
 
 func isHashAsset(ctx context.Context, client *http.Client, assetURL string, oldHash string) (bool, error) {
+	newHash, err := assetHash(ctx, client, assetURL, oldHash)
+	if err != nil {
+		return false, err
+	}
+	return newHash != "" && newHash == oldHash, nil
+}
+
+// assetHash downloads an asset, and returns its hash using the same algorithm as oldHash.
+func assetHash(ctx context.Context, client *http.Client, assetURL string, oldHash string) (string, error) {
 	h, ok := hasher(oldHash)
 	if !ok {
-		return false, nil
+		return "", nil
 	}
 
 	req, err := http.NewRequest("GET", assetURL, nil)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 	req = req.WithContext(ctx)
 
 	res, err := client.Do(req)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", nil
+	}
 
 	if _, err := io.Copy(h, res.Body); err != nil {
-		return false, err
+		return "", err
 	}
 	sum := h.Sum(nil)
 	newHash := fmt.Sprintf("%x", sum)
@@ -291,7 +559,7 @@ func isHashAsset(ctx context.Context, client *http.Client, assetURL string, oldH
 		"url":  assetURL,
 		"hash": newHash,
 	}).Debug("downloaded asset")
-	return newHash == oldHash, nil
+	return newHash, nil
 }
 
 func hasher(oldHash string) (hash.Hash, bool) {
@@ -308,8 +576,8 @@ func hasher(oldHash string) (hash.Hash, bool) {
 	}
 }
 
-func updatedHashFromAsset(ctx context.Context, client *http.Client, assetURL string, update updater.Update, oldHash string) (string, error) {
-	res, err := getUpdatedAsset(ctx, client, assetURL, update)
+func updatedHashFromAsset(ctx context.Context, client *http.Client, assetURL string, update releaseUpdate, oldHash string) (string, error) {
+	res, err := getAsset(ctx, client, update.assetURL(assetURL))
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,378 @@
+package brew
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thepwagner/action-update/updater"
+)
+
+// fakeGitHub serves the GitHub API under /api/, and release assets under /download/.
+type fakeGitHub struct {
+	*fakeServer
+	client *github.Client
+}
+
+func newFakeGitHub(t *testing.T) *fakeGitHub {
+	srv := newFakeServer(t, nil)
+	srv.mux.HandleFunc("/api/", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"message":"Not Found"}`)
+	})
+
+	client := github.NewClient(srv.Client())
+	client.BaseURL, _ = url.Parse(srv.URL + "/api/")
+	return &fakeGitHub{fakeServer: srv, client: client}
+}
+
+// release publishes a release with the given assets, returning each asset's hash.
+func (f *fakeGitHub) release(repo, tag string, assets map[string]string) map[string]string {
+	release := &github.RepositoryRelease{TagName: github.String(tag)}
+	hashes := make(map[string]string, len(assets))
+	for name, contents := range assets {
+		path := fmt.Sprintf("/download/%s/%s", tag, name)
+		f.file(path, contents)
+		release.Assets = append(release.Assets, &github.ReleaseAsset{
+			Name:               github.String(name),
+			Size:               github.Int(len(contents)),
+			BrowserDownloadURL: github.String(f.URL + path),
+		})
+		hashes[name] = sha256Hex(contents)
+	}
+	f.json(fmt.Sprintf("/api/repos/%s/releases/tags/%s", repo, tag), release)
+	return hashes
+}
+
+const platformFormula = `class Foo < Formula
+  version "1.0.0"
+
+  on_macos do
+    on_arm do
+      url "https://github.com/foo/foo/releases/download/v#{version}/foo_darwin_arm64.tar.gz"
+      sha256 "%s"
+    end
+    on_intel do
+      url "https://github.com/foo/foo/releases/download/v#{version}/foo_darwin_amd64.tar.gz"
+      sha256 "%s"
+    end
+  end
+
+  on_linux do
+    url "https://github.com/foo/foo/releases/download/v#{version}/foo_linux_amd64.tar.gz"
+    sha256 "%s"
+  end
+end
+`
+
+func platformRelease(gh *fakeGitHub, version string) map[string]string {
+	assets := map[string]string{
+		"foo_darwin_arm64.tar.gz": "darwin arm64 " + version,
+		"foo_darwin_amd64.tar.gz": "darwin amd64 " + version,
+		"foo_linux_amd64.tar.gz":  "linux amd64 " + version,
+	}
+	var shasums strings.Builder
+	for name, contents := range assets {
+		_, _ = fmt.Fprintf(&shasums, "%s  %s\n", sha256Hex(contents), name)
+	}
+	assets["SHA256SUMS"] = shasums.String()
+	return gh.release("foo/foo", "v"+version, assets)
+}
+
+func TestUpdater_Dependencies_Platforms(t *testing.T) {
+	deps, err := parseFormulaDeps(fmt.Sprintf(platformFormula, "a", "b", "c"))
+	require.NoError(t, err)
+	assert.Equal(t, []updater.Dependency{
+		{Path: "https://github.com/foo/foo/releases/download/v#{version}/foo_darwin_arm64.tar.gz", Version: "1.0.0"},
+	}, deps)
+
+	f, err := parseFormula(fmt.Sprintf(platformFormula, "a", "b", "c"))
+	require.NoError(t, err)
+	dep := formulaDeps(f)[0]
+	var platforms []string
+	for _, a := range dep.artifacts {
+		platforms = append(platforms, a.platform)
+	}
+	assert.Equal(t, []string{"on_macos/on_arm", "on_macos/on_intel", "on_linux"}, platforms)
+}
+
+func TestUpdater_Update_Platforms(t *testing.T) {
+	gh := newFakeGitHub(t)
+	prev := platformRelease(gh, "1.0.0")
+	next := platformRelease(gh, "1.1.0")
+
+	formula := fmt.Sprintf(platformFormula, prev["foo_darwin_arm64.tar.gz"], prev["foo_darwin_amd64.tar.gz"], prev["foo_linux_amd64.tar.gz"])
+	f := newFormulaFixture(t, formula)
+	f.ghRepos = gh.client.Repositories
+	updated := f.apply(&updater.Update{
+		Path:     "https://github.com/foo/foo/releases/download/v#{version}/foo_darwin_arm64.tar.gz",
+		Previous: "1.0.0",
+		Next:     "1.1.0",
+	})
+
+	expected := fmt.Sprintf(platformFormula, next["foo_darwin_arm64.tar.gz"], next["foo_darwin_amd64.tar.gz"], next["foo_linux_amd64.tar.gz"])
+	expected = strings.Replace(expected, `version "1.0.0"`, `version "1.1.0"`, 1)
+	assert.Equal(t, expected, updated)
+}
+
+const conditionalFormula = `class Foo < Formula
+  version "1.0.0"
+
+  on_macos do
+    url "https://github.com/foo/foo/releases/download/v#{version}/foo_darwin_amd64.tar.gz"
+    sha256 "%s"
+  end
+
+  on_linux do
+    if Hardware::CPU.arm?
+      url "https://github.com/foo/foo/releases/download/v#{version}/foo_linux_arm64.tar.gz"
+      sha256 "%s"
+    else
+      url "https://github.com/foo/foo/releases/download/v#{version}/foo_linux_amd64.tar.gz"
+      sha256 "%s"
+    end
+  end
+end
+`
+
+func TestUpdater_Update_PlatformConditionals(t *testing.T) {
+	gh := newFakeGitHub(t)
+	release := func(version string) map[string]string {
+		return gh.release("foo/foo", "v"+version, map[string]string{
+			"foo_darwin_amd64.tar.gz": "darwin amd64 " + version,
+			"foo_linux_arm64.tar.gz":  "linux arm64 " + version,
+			"foo_linux_amd64.tar.gz":  "linux amd64 " + version,
+		})
+	}
+	prev := release("1.0.0")
+	next := release("1.1.0")
+
+	f := newFormulaFixture(t, fmt.Sprintf(conditionalFormula, prev["foo_darwin_amd64.tar.gz"], prev["foo_linux_arm64.tar.gz"], prev["foo_linux_amd64.tar.gz"]))
+	f.ghRepos = gh.client.Repositories
+	deps := f.deps(1)
+	parsed, err := parseFormula(f.read())
+	require.NoError(t, err)
+	var platforms []string
+	for _, a := range formulaDeps(parsed)[0].artifacts {
+		platforms = append(platforms, a.platform)
+	}
+	assert.Equal(t, []string{"on_macos", "on_linux/if", "on_linux/else"}, platforms)
+
+	updated := f.apply(&updater.Update{Path: deps[0].Path, Previous: "1.0.0", Next: "1.1.0"})
+
+	expected := fmt.Sprintf(conditionalFormula, next["foo_darwin_amd64.tar.gz"], next["foo_linux_arm64.tar.gz"], next["foo_linux_amd64.tar.gz"])
+	expected = strings.Replace(expected, `version "1.0.0"`, `version "1.1.0"`, 1)
+	assert.Equal(t, expected, updated)
+}
+
+func TestUpdater_Update_PlatformsAtomic(t *testing.T) {
+	gh := newFakeGitHub(t)
+	prev := platformRelease(gh, "1.0.0")
+	// The new release is missing the linux build:
+	gh.release("foo/foo", "v1.1.0", map[string]string{
+		"foo_darwin_arm64.tar.gz": "darwin arm64 1.1.0",
+		"foo_darwin_amd64.tar.gz": "darwin amd64 1.1.0",
+	})
+
+	formula := fmt.Sprintf(platformFormula, prev["foo_darwin_arm64.tar.gz"], prev["foo_darwin_amd64.tar.gz"], prev["foo_linux_amd64.tar.gz"])
+	f := newFormulaFixture(t, formula)
+	f.ghRepos = gh.client.Repositories
+	err := f.ApplyUpdate(context.Background(), updater.Update{
+		Path:     "https://github.com/foo/foo/releases/download/v#{version}/foo_darwin_arm64.tar.gz",
+		Previous: "1.0.0",
+		Next:     "1.1.0",
+	})
+	assert.Error(t, err)
+	assert.Equal(t, formula, f.read())
+}
+
+func TestUpdater_Check_Directives(t *testing.T) {
+	gh := newFakeGitHub(t)
+	gh.json("/api/repos/foo/foo/releases", []*github.RepositoryRelease{
+		{TagName: github.String("release-1.2.0")},
+		{TagName: github.String("release-2.0.0")},
+		{TagName: github.String("v9.0.0")},
+	})
+
+	cases := map[string]struct {
+		directive string
+		next      string
+	}{
+		"source":         {directive: "# update: source=github:foo/foo tag-regex=^release-(.*)$", next: "2.0.0"},
+		"pin":            {directive: "# update: source=github:foo/foo tag-regex=^release-(.*)$ pin 1.x", next: "1.2.0"},
+		"pin exhausted":  {directive: "# update: source=github:foo/foo tag-regex=^release-(.*)$\n  # update: pin 1.0.x"},
+		"ignore":         {directive: "# update: source=github:foo/foo ignore"},
+		"untagged regex": {directive: "# update: source=github:foo/foo", next: "9.0.0"},
+	}
+	for label, tc := range cases {
+		t.Run(label, func(t *testing.T) {
+			f := newFormulaFixture(t, fmt.Sprintf("class Foo < Formula\n  %s\n  url \"https://example.com/foo-1.0.0.tar.gz\"\nend\n", tc.directive))
+			f.ghRepos = gh.client.Repositories
+			update := f.check()
+			if tc.next == "" {
+				assert.Nil(t, update)
+				return
+			}
+			require.NotNil(t, update)
+			assert.Equal(t, tc.next, update.Next)
+		})
+	}
+}
+
+func TestUpdater_Update_IgnoreDirective(t *testing.T) {
+	formula := "class Foo < Formula\n  # update: ignore\n  url \"https://example.com/foo-1.0.0.tar.gz\"\nend\n"
+	f := newFormulaFixture(t, formula)
+	updated := f.apply(&updater.Update{Path: "https://example.com/foo-1.0.0.tar.gz", Previous: "1.0.0", Next: "1.1.0"})
+	assert.Equal(t, formula, updated)
+}
+
+func TestDetectTagPattern(t *testing.T) {
+	cases := []struct {
+		url      string
+		version  string
+		template string
+	}{
+		{url: "https://github.com/foo/foo/releases/download/v#{version}/foo.tar.gz", version: "1.2.3", template: "v#{version}"},
+		{url: "https://github.com/foo/foo/releases/download/cli%2Fv1.2.3/foo-1.2.3.tar.gz", version: "1.2.3", template: "cli/v#{version}"},
+		{url: "https://github.com/foo/foo/archive/rel/1.2.tar.gz", version: "1.2", template: "rel/#{version}"},
+		{url: "https://github.com/foo/foo/archive/refs/tags/release-1_2_3.zip", version: "1.2.3", template: "release-#{version.dots_to_underscores}"},
+		{url: "https://github.com/foo/foo/archive/main.tar.gz", version: "1.2.3"},
+		{url: "https://example.com/foo/foo/archive/v1.2.3.tar.gz", version: "1.2.3"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.url, func(t *testing.T) {
+			p := detectTagPattern(tc.url, tc.version)
+			if tc.template == "" {
+				assert.Nil(t, p)
+				return
+			}
+			require.NotNil(t, p)
+			assert.Equal(t, tc.template, p.template)
+
+			tag := p.tag("2.0.1")
+			version, ok := p.version(tag)
+			assert.True(t, ok)
+			assert.Equal(t, "2.0.1", version)
+		})
+	}
+}
+
+func TestUpdater_Update_TagPattern(t *testing.T) {
+	cases := map[string]struct {
+		url       string
+		directive string
+		tag       func(version string) string
+	}{
+		"monorepo": {
+			url: "https://github.com/foo/foo/releases/download/cli%2Fv1.0.0/foo-1.0.0.tar.gz",
+			tag: func(v string) string { return "cli/v" + v },
+		},
+		"underscores": {
+			url: "https://github.com/foo/foo/releases/download/rel_1_0_0/foo-1_0_0.tar.gz",
+			tag: func(v string) string { return "rel_" + strings.ReplaceAll(v, ".", "_") },
+		},
+		"tag directive": {
+			url:       "https://github.com/foo/foo/releases/download/1.0.0/foo.tar.gz",
+			directive: "# update: tag=stable-#{version}",
+			tag:       func(v string) string { return "stable-" + v },
+		},
+		"tag-regex directive": {
+			url:       "https://github.com/foo/foo/releases/download/1.0.0/foo.tar.gz",
+			directive: "# update: tag=#{version} tag-regex=^stable-(.+)$",
+			tag:       func(v string) string { return "stable-" + v },
+		},
+	}
+	for label, tc := range cases {
+		t.Run(label, func(t *testing.T) {
+			gh := newFakeGitHub(t)
+			var releases []*github.RepositoryRelease
+			// Unrelated tags are listed first, and must not stop the search:
+			for _, tag := range []string{"nightly", "server/v9.0.0", tc.tag("1.1.0"), tc.tag("1.0.0")} {
+				releases = append(releases, &github.RepositoryRelease{TagName: github.String(tag)})
+			}
+			gh.json("/api/repos/foo/foo/releases", releases)
+			assetName := path.Base(tc.url)
+			prev := gh.release("foo/foo", tc.tag("1.0.0"), map[string]string{assetName: "foo 1.0.0"})
+			next := gh.release("foo/foo", tc.tag("1.1.0"), map[string]string{strings.NewReplacer("1.0.0", "1.1.0", "1_0_0", "1_1_0").Replace(assetName): "foo 1.1.0"})
+
+			f := newFormulaFixture(t, fmt.Sprintf("class Foo < Formula\n  %s\n  url \"%s\"\n  version \"1.0.0\"\n  sha256 \"%s\"\nend\n", tc.directive, tc.url, prev[assetName]))
+			f.ghRepos = gh.client.Repositories
+			update := f.check()
+			require.NotNil(t, update)
+			assert.Equal(t, "1.1.0", update.Next)
+
+			formula := f.apply(update)
+			for _, h := range next {
+				assert.Contains(t, formula, h)
+			}
+			assert.Contains(t, formula, `version "1.1.0"`)
+		})
+	}
+}
+
+func TestUpdater_Check_GitHubReleasePages(t *testing.T) {
+	gh := newFakeGitHub(t)
+	pages := map[string][]string{
+		"":  {"v3.0.0", "v2.1.0"},
+		"2": {"v2.0.0", "v1.0.0"},
+		"3": {"v0.9.0"},
+	}
+	var requested []string
+	gh.mux.HandleFunc("/api/repos/foo/foo/releases", func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		requested = append(requested, page)
+		var releases []*github.RepositoryRelease
+		for _, tag := range pages[page] {
+			releases = append(releases, &github.RepositoryRelease{TagName: github.String(tag)})
+		}
+		if page != "3" {
+			next := "2"
+			if page == "2" {
+				next = "3"
+			}
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/repos/foo/foo/releases?page=%s>; rel="next"`, gh.URL, next))
+		}
+		_ = json.NewEncoder(w).Encode(releases)
+	})
+
+	f := newFormulaFixture(t, "class Foo < Formula\n  url \"https://github.com/foo/foo/releases/download/v1.0.0/foo.tar.gz\"\nend\n")
+	f.ghRepos = gh.client.Repositories
+	update := f.check()
+	require.NotNil(t, update)
+	assert.Equal(t, "3.0.0", update.Next)
+	// The page containing the current version is the last one needed:
+	assert.Equal(t, []string{"", "2"}, requested)
+}
+
+func TestUpdater_Update_GitHubTags(t *testing.T) {
+	gh := newFakeGitHub(t)
+	gh.json("/api/repos/foo/foo/releases", []*github.RepositoryRelease{})
+	var tags []*github.RepositoryTag
+	hashes := map[string]string{}
+	for _, v := range []string{"1.1.0", "1.0.0"} {
+		tarball := fmt.Sprintf("/repos/foo/foo/tarball/v%s", v)
+		gh.file(tarball, "foo "+v)
+		hashes[v] = sha256Hex("foo " + v)
+		tags = append(tags, &github.RepositoryTag{
+			Name:       github.String("v" + v),
+			TarballURL: github.String(gh.URL + tarball),
+		})
+	}
+	gh.json("/api/repos/foo/foo/tags", tags)
+
+	f := newFormulaFixture(t, fmt.Sprintf("class Foo < Formula\n  url \"https://github.com/foo/foo/archive/v1.0.0.tar.gz\"\n  sha256 \"%s\"\nend\n", hashes["1.0.0"]))
+	f.ghRepos = gh.client.Repositories
+	update := f.check()
+	require.NotNil(t, update)
+	assert.Equal(t, "1.1.0", update.Next)
+	assert.Equal(t, fmt.Sprintf("class Foo < Formula\n  url \"https://github.com/foo/foo/archive/v1.1.0.tar.gz\"\n  sha256 \"%s\"\nend\n", hashes["1.1.0"]), f.apply(update))
+}
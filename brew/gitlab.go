@@ -0,0 +1,240 @@
+package brew
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/thepwagner/action-update/updater"
+)
+
+const defaultGitLabURL = "https://gitlab.com"
+
+var errGitLabNotFound = errors.New("not found")
+
+type gitlabRelease struct {
+	TagName         string    `json:"tag_name"`
+	ReleasedAt      time.Time `json:"released_at"`
+	UpcomingRelease bool      `json:"upcoming_release"`
+	Assets          struct {
+		Sources []struct {
+			Format string `json:"format"`
+			URL    string `json:"url"`
+		} `json:"sources"`
+		Links []struct {
+			Name           string `json:"name"`
+			URL            string `json:"url"`
+			DirectAssetURL string `json:"direct_asset_url"`
+		} `json:"links"`
+	} `json:"assets"`
+}
+
+type gitlabTag struct {
+	Name string `json:"name"`
+}
+
+// gitlabRepo is a project on a GitLab instance.
+type gitlabRepo struct {
+	// baseURL is the instance hosting the project, e.g. "https://gitlab.com".
+	baseURL string
+	// path is the full path of the project, e.g. "group/project".
+	path string
+}
+
+func (r gitlabRepo) String() string {
+	return r.baseURL + "/" + r.path
+}
+
+// gitlabProject returns the project a GitLab download url belongs to, e.g. "group/project" on https://gitlab.com for
+// https://gitlab.com/group/project/-/archive/v1.2.3/project-v1.2.3.tar.gz.
+func (u Updater) gitlabProject(rawURL string) (gitlabRepo, bool) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return gitlabRepo{}, false
+	}
+	base, err := url.Parse(u.gitlabURL)
+	if err != nil {
+		return gitlabRepo{}, false
+	}
+
+	var repo gitlabRepo
+	projectPath := parsed.Path
+	switch {
+	case parsed.Host == base.Host:
+		repo.baseURL = u.gitlabBaseURL()
+		projectPath = strings.TrimPrefix(projectPath, strings.TrimSuffix(base.Path, "/"))
+	case parsed.Host == "gitlab.com":
+		repo.baseURL = parsed.Scheme + "://" + parsed.Host
+	default:
+		return gitlabRepo{}, false
+	}
+	i := strings.Index(projectPath, "/-/")
+	if i < 0 {
+		return gitlabRepo{}, false
+	}
+	repo.path = strings.Trim(projectPath[:i], "/")
+	return repo, strings.Contains(repo.path, "/")
+}
+
+// gitlabBaseURL is the configured GitLab instance, without a trailing slash.
+func (u Updater) gitlabBaseURL() string {
+	return strings.TrimSuffix(u.gitlabURL, "/")
+}
+
+// gitlabSource discovers the releases of GitLab projects, on gitlab.com or a self-managed instance.
+type gitlabSource struct{ u *Updater }
+
+func (s gitlabSource) Match(dep updater.Dependency) bool {
+	_, ok := s.u.gitlabProject(dep.Path)
+	return ok
+}
+
+func (s gitlabSource) ListVersions(ctx context.Context, q VersionQuery) ([]SourceVersion, error) {
+	fd := q.formulaDep()
+	project, _ := s.u.gitlabProject(q.Path)
+	releases, err := s.u.listGitLabReleases(ctx, project, q.Channel, fd.currentRelease)
+	if err != nil {
+		return nil, err
+	}
+	dates := versionDates{}
+	versions := fd.releaseVersions(releases, dates)
+	return sourceVersions(versions, dates), nil
+}
+
+func (s gitlabSource) ResolveHash(ctx context.Context, update updater.Update, oldHash string) (string, error) {
+	dep := &formulaDep{Dependency: updater.Dependency{Path: update.Path, Version: update.Previous}}
+	newHashes, err := s.resolveHashes(ctx, dep, update, []string{oldHash})
+	if err != nil {
+		return "", err
+	}
+	return newHashes[oldHash], nil
+}
+
+func (s gitlabSource) resolveHashes(ctx context.Context, dep *formulaDep, update updater.Update, oldHashes []string) (map[string]string, error) {
+	ru, err := s.u.releaseUpdate(ctx, dep, update)
+	if err != nil {
+		return nil, err
+	}
+	project, _ := s.u.gitlabProject(update.Path)
+	files, err := s.u.gitlabReleaseFiles(ctx, project, ru.previousTag)
+	if err != nil {
+		return nil, err
+	}
+	return updatedReleaseHashes(ctx, s.u.client, files, ru, oldHashes)
+}
+
+// listGitLabReleases lists a project's releases on the channel, newest first. Upcoming releases are never listed.
+// If current is provided, pages are fetched until it matches a tag. Projects without releases fall back to listing
+// their tags.
+func (u Updater) listGitLabReleases(ctx context.Context, project gitlabRepo, ch Channel, current func(tag string) bool) ([]taggedRelease, error) {
+	log := logrus.WithField("project", project)
+	projectPath := "projects/" + url.PathEscape(project.path)
+
+	var ret []taggedRelease
+	var listed int
+	for page := "1"; page != ""; {
+		var releases []gitlabRelease
+		next, err := u.gitlabGet(ctx, project.baseURL, projectPath+"/releases?per_page=100&page="+page, &releases)
+		if err != nil {
+			return nil, fmt.Errorf("querying for releases: %w", err)
+		}
+		listed += len(releases)
+		found := false
+		for _, release := range releases {
+			found = found || (current != nil && current(release.TagName))
+			if release.UpcomingRelease || !ch.allows(release.TagName) {
+				log.WithField("tag", release.TagName).Debug("skipping release outside channel")
+				continue
+			}
+			ret = append(ret, taggedRelease{tag: release.TagName, published: release.ReleasedAt})
+		}
+		if found {
+			break
+		}
+		page = next
+	}
+	log.WithField("releases", listed).Debug("fetched releases")
+	if listed > 0 {
+		return ret, nil
+	}
+
+	var tagCount int
+	for page := "1"; page != ""; {
+		var tags []gitlabTag
+		next, err := u.gitlabGet(ctx, project.baseURL, projectPath+"/repository/tags?per_page=100&page="+page, &tags)
+		if err != nil {
+			return nil, fmt.Errorf("querying for tags: %w", err)
+		}
+		tagCount += len(tags)
+		for _, tag := range tags {
+			if ch.allows(tag.Name) {
+				ret = append(ret, taggedRelease{tag: tag.Name})
+			}
+		}
+		page = next
+	}
+	log.WithField("tags", tagCount).Debug("no releases found, fetched tags")
+	return ret, nil
+}
+
+// gitlabReleaseFiles returns the files of a release: its links and source archives. Tags without a release only have
+// source archives.
+func (u Updater) gitlabReleaseFiles(ctx context.Context, project gitlabRepo, tag string) (releaseFiles, error) {
+	var release gitlabRelease
+	_, err := u.gitlabGet(ctx, project.baseURL, "projects/"+url.PathEscape(project.path)+"/releases/"+url.PathEscape(tag), &release)
+	if errors.Is(err, errGitLabNotFound) {
+		logrus.WithField("tag", tag).Debug("release not found, using tag")
+		archive := fmt.Sprintf("%s/-/archive/%s/%s-%s", project, tag, path.Base(project.path), strings.ReplaceAll(tag, "/", "-"))
+		return releaseFiles{sources: []string{archive + ".tar.gz", archive + ".zip"}}, nil
+	} else if err != nil {
+		return releaseFiles{}, fmt.Errorf("querying release %s: %w", tag, err)
+	}
+
+	var files releaseFiles
+	for _, link := range release.Assets.Links {
+		assetURL := link.DirectAssetURL
+		if assetURL == "" {
+			assetURL = link.URL
+		}
+		files.assets = append(files.assets, releaseAsset{name: link.Name, url: assetURL})
+	}
+	for _, source := range release.Assets.Sources {
+		files.sources = append(files.sources, source.URL)
+	}
+	return files, nil
+}
+
+// gitlabGet decodes a response from the API of a GitLab instance, returning the next page of results if there is one.
+// The token is only sent to the configured instance.
+func (u Updater) gitlabGet(ctx context.Context, baseURL, apiPath string, v interface{}) (string, error) {
+	req, err := http.NewRequest("GET", baseURL+"/api/v4/"+apiPath, nil)
+	if err != nil {
+		return "", err
+	}
+	if u.gitlabToken != "" && baseURL == u.gitlabBaseURL() {
+		req.Header.Set("PRIVATE-TOKEN", u.gitlabToken)
+	}
+	res, err := u.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", errGitLabNotFound
+	default:
+		return "", fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return "", err
+	}
+	return res.Header.Get("X-Next-Page"), nil
+}
@@ -0,0 +1,148 @@
+package brew
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitLab serves the GitLab API for the project foo/foo under /api/v4/, and any files.
+type fakeGitLab struct {
+	*fakeServer
+	// token is required by the API, which rejects any other token.
+	token string
+	api   map[string]interface{}
+	// nextPages links API paths to the page after them.
+	nextPages map[string]string
+}
+
+func newFakeGitLab(t *testing.T) *fakeGitLab {
+	f := &fakeGitLab{fakeServer: newFakeServer(t, nil), token: "secret", api: map[string]interface{}{}, nextPages: map[string]string{}}
+	f.mux.HandleFunc("/api/v4/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != f.token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		key := r.URL.EscapedPath()
+		if page := r.URL.Query().Get("page"); page != "" && page != "1" {
+			key += "?page=" + page
+		}
+		v, ok := f.api[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if next, ok := f.nextPages[key]; ok {
+			w.Header().Set("X-Next-Page", next)
+		}
+		_ = json.NewEncoder(w).Encode(v)
+	})
+	return f
+}
+
+func (f *fakeGitLab) release(tag string, links map[string]string) map[string]interface{} {
+	var assetLinks []map[string]string
+	for name, contents := range links {
+		p := fmt.Sprintf("/foo/foo/-/releases/%s/downloads/%s", tag, name)
+		f.file(p, contents)
+		assetLinks = append(assetLinks, map[string]string{"name": name, "url": f.URL + p, "direct_asset_url": f.URL + p})
+	}
+	release := map[string]interface{}{
+		"tag_name":    tag,
+		"released_at": "2020-01-01T00:00:00Z",
+		"assets":      map[string]interface{}{"links": assetLinks},
+	}
+	f.api["/api/v4/projects/foo%2Ffoo/releases/"+tag] = release
+	return release
+}
+
+// tags publishes tags without releases, and their source archives.
+func (f *fakeGitLab) tags(versions ...string) {
+	f.api["/api/v4/projects/foo%2Ffoo/releases"] = []interface{}{}
+	var tags []gitlabTag
+	for _, v := range versions {
+		tags = append(tags, gitlabTag{Name: "v" + v})
+		f.file(fmt.Sprintf("/foo/foo/-/archive/v%s/foo-v%s.tar.gz", v, v), "foo "+v)
+	}
+	f.api["/api/v4/projects/foo%2Ffoo/repository/tags"] = tags
+}
+
+func TestUpdater_Update_GitLab(t *testing.T) {
+	gl := newFakeGitLab(t)
+	var releases []interface{}
+	for _, v := range []string{"1.1.0", "1.0.0"} {
+		contents := "foo " + v
+		releases = append(releases, gl.release("v"+v, map[string]string{
+			"foo-" + v + ".tar.gz": contents,
+			"SHA256SUMS":           fmt.Sprintf("%s  foo-%s.tar.gz\n", sha256Hex(contents), v),
+		}))
+	}
+	// The current release is on the second page:
+	gl.api["/api/v4/projects/foo%2Ffoo/releases"] = releases[:1]
+	gl.nextPages["/api/v4/projects/foo%2Ffoo/releases"] = "2"
+	gl.api["/api/v4/projects/foo%2Ffoo/releases?page=2"] = releases[1:]
+	// Break the asset, so the checksum file must be used:
+	gl.file("/foo/foo/-/releases/v1.1.0/downloads/foo-1.1.0.tar.gz", "tampered")
+
+	const formula = "class Foo < Formula\n  url \"%s/foo/foo/-/releases/v%s/downloads/foo-%[2]s.tar.gz\"\n  sha256 \"%s\"\nend\n"
+	f := newFormulaFixture(t, fmt.Sprintf(formula, gl.URL, "1.0.0", sha256Hex("foo 1.0.0")), WithGitLab(gl.URL, "secret"))
+	update := f.check()
+	require.NotNil(t, update)
+	assert.Equal(t, "1.1.0", update.Next)
+	assert.Equal(t, fmt.Sprintf(formula, gl.URL, "1.1.0", sha256Hex("foo 1.1.0")), f.apply(update))
+}
+
+func TestUpdater_Update_GitLabTags(t *testing.T) {
+	gl := newFakeGitLab(t)
+	gl.tags("1.1.0-rc1", "1.0.1", "1.0.0")
+
+	const formula = "class Foo < Formula\n  url \"%s/foo/foo/-/archive/v%s/foo-v%[2]s.tar.gz\"\n  sha256 \"%s\"\nend\n"
+	f := newFormulaFixture(t, fmt.Sprintf(formula, gl.URL, "1.0.0", sha256Hex("foo 1.0.0")), WithGitLab(gl.URL, "secret"))
+	update := f.check()
+	require.NotNil(t, update)
+	assert.Equal(t, "1.0.1", update.Next)
+	assert.Equal(t, fmt.Sprintf(formula, gl.URL, "1.0.1", sha256Hex("foo 1.0.1")), f.apply(update))
+}
+
+func TestUpdater_Update_GitLabPublic(t *testing.T) {
+	// gitlab.com is public, the token for the self-managed instance must not be sent:
+	gl := newFakeGitLab(t)
+	gl.token = ""
+	gl.tags("1.0.1", "1.0.0")
+	target, _ := url.Parse(gl.URL)
+
+	const formula = "class Foo < Formula\n  url \"https://gitlab.com/foo/foo/-/archive/v%s/foo-v%[1]s.tar.gz\"\n  sha256 \"%s\"\nend\n"
+	f := newFormulaFixture(t, fmt.Sprintf(formula, "1.0.0", sha256Hex("foo 1.0.0")), WithGitLab("https://gitlab.example.com", "secret"))
+	f.client = &http.Client{Transport: redirectTransport{target: target}}
+	update := f.check()
+	require.NotNil(t, update)
+	assert.Equal(t, "1.0.1", update.Next)
+	assert.Equal(t, fmt.Sprintf(formula, "1.0.1", sha256Hex("foo 1.0.1")), f.apply(update))
+}
+
+func TestUpdater_GitLabProject(t *testing.T) {
+	u := NewUpdater("", WithGitLab("https://example.com/gitlab/", ""))
+	cases := map[string]gitlabRepo{
+		"https://gitlab.com/foo/foo/-/archive/v1.0.0/foo-v1.0.0.tar.gz":         {baseURL: "https://gitlab.com", path: "foo/foo"},
+		"https://example.com/gitlab/foo/bar/-/archive/v1.0.0/bar-v1.0.0.tar.gz": {baseURL: "https://example.com/gitlab", path: "foo/bar"},
+	}
+	for rawURL, expected := range cases {
+		project, ok := u.gitlabProject(rawURL)
+		assert.True(t, ok, rawURL)
+		assert.Equal(t, expected, project)
+	}
+
+	for _, rawURL := range []string{
+		"https://github.com/foo/foo/archive/v1.0.0.tar.gz",
+		"https://gitlab.example.com/foo/foo/-/archive/v1.0.0/foo-v1.0.0.tar.gz",
+		"https://gitlab.com/foo/-/archive/v1.0.0/foo-v1.0.0.tar.gz",
+	} {
+		_, ok := u.gitlabProject(rawURL)
+		assert.False(t, ok, rawURL)
+	}
+}
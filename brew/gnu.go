@@ -0,0 +1,326 @@
+package brew
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+	"github.com/thepwagner/action-update/updater"
+)
+
+// gnuMirror is a host of GNU-style release directories, like /gnu/<pkg>/<pkg>-1.2.tar.xz and its detached .sig.
+type gnuMirror struct {
+	prefix string
+	// keyring is the url of the keys that sign releases. "%s" is replaced by the package.
+	keyring string
+}
+
+const gnuKeyringURL = "https://ftp.gnu.org/gnu/gnu-keyring.gpg"
+
+var defaultGNUMirrors = []gnuMirror{
+	{prefix: "https://ftp.gnu.org/gnu/", keyring: gnuKeyringURL},
+	{prefix: "https://ftpmirror.gnu.org/", keyring: gnuKeyringURL},
+	{prefix: "https://mirrors.kernel.org/gnu/", keyring: gnuKeyringURL},
+	{prefix: "https://download.savannah.gnu.org/releases/", keyring: "https://savannah.gnu.org/project/release-gpgkeys.php?group=%s&download=1"},
+	{prefix: "https://download.savannah.nongnu.org/releases/", keyring: "https://savannah.nongnu.org/project/release-gpgkeys.php?group=%s&download=1"},
+}
+
+// gnuExtensions are the archive formats GNU releases are published in. Formulae keep their format while it is
+// published, then prefer the first of gnuFallbackExtensions: those Homebrew unpacks without a build dependency.
+var (
+	gnuExtensions         = []string{".tar.gz", ".tar.xz", ".tar.bz2", ".tar.lz", ".tar.zst", ".tgz", ".zip"}
+	gnuFallbackExtensions = []string{".tar.xz", ".tar.bz2", ".tar.gz"}
+)
+
+// gnuArtifact is a release archive on a GNU mirror.
+type gnuArtifact struct {
+	mirror gnuMirror
+	// pkg is the directory of the package on the mirror, e.g. "grep".
+	pkg string
+	// listing is the url of the directory listing versions.
+	listing string
+	// prefix precedes the version in the listed entries, e.g. "grep-".
+	prefix string
+	// ext is the format of the archive, or empty if the listing holds a directory per version (e.g. gcc-13.2.0/).
+	ext string
+}
+
+// gnuArtifact identifies an archive downloaded from a GNU mirror.
+func (u Updater) gnuArtifact(rawURL, version string) (*gnuArtifact, bool) {
+	var mirror gnuMirror
+	for _, m := range u.gnuMirrors {
+		if strings.HasPrefix(rawURL, m.prefix) {
+			mirror = m
+			break
+		}
+	}
+	if mirror.prefix == "" || version == "" {
+		return nil, false
+	}
+
+	segments := strings.Split(strings.TrimPrefix(rawURL, mirror.prefix), "/")
+	for i, segment := range segments {
+		j := strings.Index(segment, version)
+		if j < 0 {
+			continue
+		}
+		a := &gnuArtifact{
+			mirror:  mirror,
+			pkg:     segments[0],
+			listing: mirror.prefix + strings.Join(segments[:i], "/") + "/",
+			prefix:  segment[:j],
+		}
+		rest := segment[j+len(version):]
+		if i < len(segments)-1 {
+			return a, i > 0 && rest == ""
+		}
+		for _, ext := range gnuExtensions {
+			if rest == ext {
+				a.ext = ext
+				return a, i > 0
+			}
+		}
+		return nil, false
+	}
+	return nil, false
+}
+
+// gnuSource discovers releases from the directory listings of GNU mirrors and Savannah.
+type gnuSource struct{ u *Updater }
+
+func (s gnuSource) Match(dep updater.Dependency) bool {
+	_, ok := s.u.gnuArtifact(expandVersion(dep.Path, dep.Version), dep.Version)
+	return ok
+}
+
+func (s gnuSource) ListVersions(ctx context.Context, q VersionQuery) ([]SourceVersion, error) {
+	a, _ := s.u.gnuArtifact(expandVersion(q.Path, q.Version), q.Version)
+	releases, err := s.u.listGNUReleases(ctx, a, q.Scheme.Pattern())
+	if err != nil {
+		return nil, err
+	}
+
+	// Templates can't change format, so only list versions published in the current format:
+	fixedFormat := interpolationRe.MatchString(q.Path)
+	versions := make([]SourceVersion, 0, len(releases))
+	for version, formats := range releases {
+		if _, ok := formats[a.ext]; fixedFormat && !ok {
+			continue
+		}
+		published, ok := formats.preferred(a.ext)
+		if !ok {
+			logrus.WithField("version", version).Debug("skipping version without a supported archive format")
+			continue
+		}
+		versions = append(versions, SourceVersion{Version: version, Published: formats[published]})
+	}
+	return versions, nil
+}
+
+// gnuFormats are the archive formats a version was published in, with when each was published.
+type gnuFormats map[string]time.Time
+
+// preferred returns the format to update to: the current format if it was published, or else a fallback.
+func (f gnuFormats) preferred(current string) (string, bool) {
+	if _, ok := f[current]; ok {
+		return current, true
+	}
+	for _, ext := range gnuFallbackExtensions {
+		if _, ok := f[ext]; ok {
+			return ext, true
+		}
+	}
+	return "", false
+}
+
+// listGNUReleases lists the versions matching a pattern in an artifact's directory listing, with the formats each was
+// published in. Directories holding a version are listed in the format of the artifact.
+func (u Updater) listGNUReleases(ctx context.Context, a *gnuArtifact, versionPattern string) (map[string]gnuFormats, error) {
+	req, err := http.NewRequest("GET", a.listing, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := u.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching listing %s: unexpected status %d", a.listing, res.StatusCode)
+	}
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Anchor entries, so "libfoo-1.2.tar.gz" and "foo-1.2.tar.gz.sig" aren't releases of foo:
+	suffix := "/?"
+	if a.ext != "" {
+		exts := make([]string, 0, len(gnuExtensions))
+		for _, ext := range gnuExtensions {
+			exts = append(exts, regexp.QuoteMeta(ext))
+		}
+		suffix = "(" + strings.Join(exts, "|") + ")"
+	}
+	entryRe := regexp.MustCompile("^" + regexp.QuoteMeta(a.prefix) + "(" + versionPattern + ")" + suffix + "$")
+
+	releases := map[string]gnuFormats{}
+	doc.Find("a[href]").Each(func(_ int, link *goquery.Selection) {
+		href, _ := link.Attr("href")
+		entry, err := url.PathUnescape(path.Base(strings.TrimSuffix(href, "/")))
+		if err != nil {
+			return
+		}
+		m := entryRe.FindStringSubmatch(entry)
+		if m == nil {
+			return
+		}
+		ext := a.ext
+		if ext != "" {
+			ext = m[2]
+		}
+		if releases[m[1]] == nil {
+			releases[m[1]] = gnuFormats{}
+		}
+		releases[m[1]][ext] = listingDate(link)
+	})
+	logrus.WithFields(logrus.Fields{
+		"listing":  a.listing,
+		"versions": len(releases),
+	}).Debug("fetched gnu releases")
+	return releases, nil
+}
+
+// nextURL returns the url of the next version's archive, changing its format if the current one wasn't published.
+func (s gnuSource) nextURL(ctx context.Context, update updater.Update) (string, error) {
+	newURL := updatedTemplateURL(update.Path, update)
+	a, ok := s.u.gnuArtifact(expandVersion(update.Path, update.Previous), update.Previous)
+	if !ok || a.ext == "" || interpolationRe.MatchString(update.Path) {
+		return newURL, nil
+	}
+
+	releases, err := s.u.listGNUReleases(ctx, a, regexp.QuoteMeta(nextVersion(update)))
+	if err != nil {
+		return "", err
+	}
+	ext, ok := releases[nextVersion(update)].preferred(a.ext)
+	if !ok {
+		return "", fmt.Errorf("no supported archive of %s found in %s", nextVersion(update), a.listing)
+	}
+	if ext != a.ext {
+		logrus.WithFields(logrus.Fields{
+			"previous": a.ext,
+			"next":     ext,
+		}).Info("archive format not published, switching format")
+	}
+	return strings.TrimSuffix(newURL, a.ext) + ext, nil
+}
+
+// ResolveHash downloads the next version's archive. If GPG is enabled, its detached signature is verified against
+// the mirror's keyring.
+func (s gnuSource) ResolveHash(ctx context.Context, update updater.Update, oldHash string) (string, error) {
+	h, ok := hasher(oldHash)
+	if !ok {
+		return "", nil
+	}
+	newURL, err := s.nextURL(ctx, update)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := getAsset(ctx, s.u.client, newURL)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if !s.u.gpg {
+		if _, err := io.Copy(h, res.Body); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%x", h.Sum(nil)), nil
+	}
+
+	dir, err := ioutil.TempDir("", "signature-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, path.Base(newURL))
+	f, err := os.OpenFile(archive, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(io.MultiWriter(h, f), res.Body)
+	f.Close()
+	if err != nil {
+		return "", err
+	}
+
+	a, _ := s.u.gnuArtifact(expandVersion(update.Path, update.Previous), update.Previous)
+	if err := s.verifySignature(ctx, a, dir, archive, newURL+".sig"); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// verifySignature verifies a downloaded archive against its detached binary signature, using a temporary GPG home
+// that trusts only the mirror's keyring. GNU releases are signed, so a missing signature is an error.
+func (s gnuSource) verifySignature(ctx context.Context, a *gnuArtifact, dir, archive, sigURL string) error {
+	sig, err := fetchOptional(ctx, s.u.client, sigURL)
+	if err != nil {
+		return err
+	} else if sig == nil {
+		return fmt.Errorf("signature %s not found", sigURL)
+	}
+	keyringURL := a.mirror.keyring
+	if strings.Contains(keyringURL, "%s") {
+		keyringURL = fmt.Sprintf(keyringURL, url.QueryEscape(a.pkg))
+	}
+	keyring, err := fetchOptional(ctx, s.u.client, keyringURL)
+	if err != nil {
+		return err
+	} else if keyring == nil {
+		return fmt.Errorf("keyring %s not found", keyringURL)
+	}
+
+	if err := verifyWithKeyring(ctx, dir, keyring, sig, archive); err != nil {
+		return err
+	}
+	logrus.WithField("signature", sigURL).Debug("verified signature")
+	return nil
+}
+
+// verifyWithKeyring verifies a file against its detached signature, using a temporary GPG home within dir that trusts
+// only the given keyring.
+func verifyWithKeyring(ctx context.Context, dir string, keyring, sig []byte, file string) error {
+	home, err := ioutil.TempDir(dir, "gnupg-*")
+	if err != nil {
+		return err
+	}
+	sigFile, keyringFile := file+".sig", filepath.Join(home, "keyring.gpg")
+	if err := ioutil.WriteFile(sigFile, sig, 0600); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(keyringFile, keyring, 0600); err != nil {
+		return err
+	}
+	for _, args := range [][]string{{"--import", keyringFile}, {"--verify", sigFile, file}} {
+		cmd := exec.CommandContext(ctx, "gpg", append([]string{"--homedir", home, "--batch"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("gpg %s: %w: %s", args[0], err, out)
+		}
+	}
+	return nil
+}
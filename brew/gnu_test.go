@@ -0,0 +1,150 @@
+package brew
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thepwagner/action-update/updater"
+)
+
+// fakeGNUMirror serves files beneath /gnu/, with listings of each directory.
+func fakeGNUMirror(t *testing.T, files map[string]string) *fakeServer {
+	srv := newFakeServer(t, files)
+	srv.mux.HandleFunc("/gnu/", func(w http.ResponseWriter, r *http.Request) {
+		if contents, ok := srv.files[r.URL.Path]; ok {
+			_, _ = io.WriteString(w, contents)
+			return
+		}
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var entries []string
+		for p := range srv.files {
+			if strings.HasPrefix(p, r.URL.Path) && !strings.Contains(p[len(r.URL.Path):], "/") {
+				entries = append(entries, p[len(r.URL.Path):])
+			}
+		}
+		sort.Strings(entries)
+		_, _ = fmt.Fprint(w, "<html><body><pre>\n")
+		for _, e := range entries {
+			_, _ = fmt.Fprintf(w, "<a href=\"%s\">%s</a>  2023-01-01 12:00  1K\n", e, e)
+		}
+		_, _ = fmt.Fprint(w, "</pre></body></html>\n")
+	})
+	return srv
+}
+
+func TestUpdater_GNU(t *testing.T) {
+	srv := fakeGNUMirror(t, map[string]string{
+		"/gnu/foo/foo-1.2.tar.gz":     "foo 1.2",
+		"/gnu/foo/foo-1.2.tar.gz.sig": "signature",
+		"/gnu/foo/foo-1.3.tar.gz":     "foo 1.3",
+		// 1.4 dropped gzip, and lzip needs a build dependency:
+		"/gnu/foo/foo-1.4.tar.lz":     "foo 1.4 lz",
+		"/gnu/foo/foo-1.4.tar.xz":     "foo 1.4 xz",
+		"/gnu/foo/foo-1.4.tar.xz.sig": "signature",
+		"/gnu/foo/libfoo-9.0.tar.gz":  "libfoo 9.0",
+		"/gnu/foo/foo-latest.tar.gz":  "foo 1.4",
+	})
+
+	const formula = "class Foo < Formula\n  url \"%[1]s/gnu/foo/foo-%[2]s\"\n  mirror \"%[1]s/gnu/foo/foo-%[2]s\"\n  sha256 \"%[3]s\"\nend\n"
+	f := newFormulaFixture(t, fmt.Sprintf(formula, srv.URL, "1.2.tar.gz", sha256Hex("foo 1.2")), WithGNUMirror(srv.URL+"/gnu"))
+	update := f.check()
+	require.NotNil(t, update)
+	assert.Equal(t, "1.4", update.Next)
+	assert.Equal(t, fmt.Sprintf(formula, srv.URL, "1.4.tar.xz", sha256Hex("foo 1.4 xz")), f.apply(update))
+}
+
+// newTestSigner generates a signing key, returning the exported public key and a function producing detached
+// signatures. The test is skipped if gpg isn't installed.
+func newTestSigner(t *testing.T) (string, func(contents string) string) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+	home := t.TempDir()
+	gpg := func(args ...string) []byte {
+		out, err := exec.Command("gpg", append([]string{"--homedir", home, "--batch", "--passphrase", ""}, args...)...).Output()
+		require.NoError(t, err)
+		return out
+	}
+	gpg("--quick-gen-key", "Foo Maintainer <foo@example.com>", "ed25519", "sign", "never")
+	sign := func(contents string) string {
+		p := filepath.Join(home, "signed")
+		require.NoError(t, ioutil.WriteFile(p, []byte(contents), 0600))
+		return string(gpg("--detach-sign", "--output", "-", p))
+	}
+	return string(gpg("--export")), sign
+}
+
+func TestUpdater_Update_GNUSignature(t *testing.T) {
+	keyring, sign := newTestSigner(t)
+	cases := map[string]struct {
+		signature string
+		valid     bool
+	}{
+		"valid":    {signature: sign("foo 1.3"), valid: true},
+		"tampered": {signature: sign("tampered"), valid: false},
+		"missing":  {valid: false},
+	}
+	for label, tc := range cases {
+		t.Run(label, func(t *testing.T) {
+			files := map[string]string{
+				"/gnu/gnu-keyring.gpg":    keyring,
+				"/gnu/foo/foo-1.2.tar.gz": "foo 1.2",
+				"/gnu/foo/foo-1.3.tar.gz": "foo 1.3",
+			}
+			if tc.signature != "" {
+				files["/gnu/foo/foo-1.3.tar.gz.sig"] = tc.signature
+			}
+			srv := fakeGNUMirror(t, files)
+			const formula = "class Foo < Formula\n  url \"%s/gnu/foo/foo-%s.tar.gz\"\n  sha256 \"%s\"\nend\n"
+			f := newFormulaFixture(t, fmt.Sprintf(formula, srv.URL, "1.2", sha256Hex("foo 1.2")), WithGNUMirror(srv.URL+"/gnu"), WithGPG(true))
+			err := f.ApplyUpdate(context.Background(), updater.Update{Path: srv.URL + "/gnu/foo/foo-1.2.tar.gz", Previous: "1.2", Next: "1.3"})
+			if tc.valid {
+				require.NoError(t, err)
+				assert.Equal(t, fmt.Sprintf(formula, srv.URL, "1.3", sha256Hex("foo 1.3")), f.read())
+			} else {
+				assert.Error(t, err)
+				assert.Equal(t, fmt.Sprintf(formula, srv.URL, "1.2", sha256Hex("foo 1.2")), f.read())
+			}
+		})
+	}
+}
+
+func TestUpdater_GNUArtifact(t *testing.T) {
+	u := NewUpdater("")
+	cases := map[string]*gnuArtifact{
+		"https://ftp.gnu.org/gnu/grep/grep-3.11.tar.xz": {
+			mirror: defaultGNUMirrors[0], pkg: "grep", listing: "https://ftp.gnu.org/gnu/grep/", prefix: "grep-", ext: ".tar.xz",
+		},
+		"https://ftp.gnu.org/gnu/gcc/gcc-3.11/gcc-3.11.tar.xz": {
+			mirror: defaultGNUMirrors[0], pkg: "gcc", listing: "https://ftp.gnu.org/gnu/gcc/", prefix: "gcc-",
+		},
+		"https://download.savannah.gnu.org/releases/acl/acl-3.11.tar.gz": {
+			mirror: defaultGNUMirrors[3], pkg: "acl", listing: "https://download.savannah.gnu.org/releases/acl/", prefix: "acl-", ext: ".tar.gz",
+		},
+		"https://ftp.gnu.org/gnu/grep/grep-3.11.tar.xz.sig": nil,
+		"https://ftp.gnu.org/gnu/grep-3.11.tar.xz":          nil,
+		"https://example.com/gnu/grep/grep-3.11.tar.xz":     nil,
+	}
+	for rawURL, expected := range cases {
+		t.Run(rawURL, func(t *testing.T) {
+			a, ok := u.gnuArtifact(rawURL, "3.11")
+			assert.Equal(t, expected != nil, ok)
+			if expected != nil {
+				assert.Equal(t, expected, a)
+			}
+		})
+	}
+}
@@ -3,64 +3,101 @@ package brew
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/sirupsen/logrus"
 	"github.com/thepwagner/action-update/updater"
-	"golang.org/x/mod/semver"
 )
 
-const (
-	golangIndexURL            = "https://golang.org/dl/?mode=json"
-	golangIndexWithHistoryURL = "https://raw.githubusercontent.com/WillAbides/goreleases/main/releases.json"
-)
+// golangIndexURL lists every Go release, including prereleases (which aren't stable) and the hashes of their files.
+const golangIndexURL = "https://go.dev/dl/?mode=json&include=all"
+
+// golangURLPrefixes are the hosts Go releases are downloaded from.
+var golangURLPrefixes = []string{
+	"https://golang.org/dl/go",
+	"https://go.dev/dl/go",
+	"https://dl.google.com/go/go",
+}
+
+func isGolangURL(u string) bool {
+	for _, prefix := range golangURLPrefixes {
+		if strings.HasPrefix(u, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// golangSource discovers Go releases from the official release index.
+type golangSource struct{ u *Updater }
+
+func (s golangSource) Match(dep updater.Dependency) bool {
+	return isGolangURL(dep.Path)
+}
+
+func (s golangSource) ListVersions(ctx context.Context, q VersionQuery) ([]SourceVersion, error) {
+	versions, err := listGolangVersions(ctx, s.u.client, q.Channel)
+	if err != nil {
+		return nil, err
+	}
+	return sourceVersions(versions, nil), nil
+}
+
+func (s golangSource) ResolveHash(ctx context.Context, update updater.Update, oldHash string) (string, error) {
+	return updatedGolangHash(ctx, s.u.client, update, oldHash)
+}
 
 type golangIndexedVersion struct {
 	Version string `json:"version"`
+	Stable  bool   `json:"stable"`
 	Files   []struct {
 		Filename string `json:"filename"`
 		Sha256   string `json:"sha256"`
 	}
 }
 
-func checkGolangRelease(ctx context.Context, client *http.Client, dep updater.Dependency) (*updater.Update, error) {
+// listGolangVersions lists the Go releases on the channel. Releases that aren't stable are release candidates or betas.
+func listGolangVersions(ctx context.Context, client *http.Client, ch Channel) ([]string, error) {
 	versions, err := fetchGolangIndex(ctx, client, golangIndexURL)
 	if err != nil {
 		return nil, err
 	}
 
-	depVersion := semverIsh(dep.Version)
+	ret := make([]string, 0, len(versions))
 	for _, v := range versions {
-		version := semverIsh(v.Version[2:])
-		if semver.Compare(version, depVersion) > 0 {
-			return &updater.Update{
-				Path:     dep.Path,
-				Previous: dep.Version,
-				Next:     v.Version[2:],
-			}, nil
+		version := strings.TrimPrefix(v.Version, "go")
+		if releaseChannel(version, !v.Stable) > ch {
+			continue
 		}
+		ret = append(ret, version)
 	}
-	return nil, nil
+	return ret, nil
 }
 
 func updatedGolangHash(ctx context.Context, client *http.Client, update updater.Update, oldHash string) (string, error) {
-	historic, err := historicVersion(ctx, client, oldHash)
+	versions, err := fetchGolangIndex(ctx, client, golangIndexURL)
 	if err != nil {
 		return "", err
 	}
+
+	var historic string
+	for _, v := range versions {
+		for _, f := range v.Files {
+			if f.Sha256 == oldHash {
+				historic = f.Filename
+			}
+		}
+	}
 	if historic == "" {
 		return "", nil
 	}
 	logrus.WithField("historic", historic).Debug("found old hash on artifact")
 
-	versions, err := fetchGolangIndex(ctx, client, golangIndexURL)
-	if err != nil {
-		return "", err
-	}
 	targetFn := strings.ReplaceAll(historic, update.Previous, update.Next)
 	for _, v := range versions {
-		if v.Version[2:] != update.Next {
+		if strings.TrimPrefix(v.Version, "go") != update.Next {
 			continue
 		}
 		for _, f := range v.Files {
@@ -74,22 +111,6 @@ func updatedGolangHash(ctx context.Context, client *http.Client, update updater.
 	return "", nil
 }
 
-func historicVersion(ctx context.Context, client *http.Client, oldHash string) (string, error) {
-	historic, err := fetchGolangIndex(ctx, client, golangIndexWithHistoryURL)
-	if err != nil {
-		return "", err
-	}
-	for _, indexedVersion := range historic {
-		for _, f := range indexedVersion.Files {
-			if f.Sha256 == oldHash {
-				return f.Filename, nil
-			}
-		}
-	}
-
-	return "", nil
-}
-
 func fetchGolangIndex(ctx context.Context, client *http.Client, indexURL string) ([]golangIndexedVersion, error) {
 	req, err := http.NewRequest("GET", indexURL, nil)
 	if err != nil {
@@ -102,6 +123,9 @@ func fetchGolangIndex(ctx context.Context, client *http.Client, indexURL string)
 		return nil, err
 	}
 	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", indexURL, res.Status)
+	}
 
 	var versions []golangIndexedVersion
 	if err := json.NewDecoder(res.Body).Decode(&versions); err != nil {
@@ -0,0 +1,71 @@
+package brew
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// redirectTransport sends every request to a test server.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = t.target.Scheme, t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func fakeGolangIndex(t *testing.T) *http.Client {
+	srv := newFakeServer(t, nil)
+	srv.mux.HandleFunc("/dl/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("mode") != "json" || r.URL.Query().Get("include") != "all" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = fmt.Fprint(w, `[
+  {"version": "go1.22rc1", "stable": false, "files": [{"filename": "go1.22rc1.linux-amd64.tar.gz", "sha256": "cccc"}]},
+  {"version": "go1.21.1", "stable": true, "files": [
+    {"filename": "go1.21.1.darwin-amd64.tar.gz", "sha256": "bbbb"},
+    {"filename": "go1.21.1.linux-amd64.tar.gz", "sha256": "bbbb1111"}
+  ]},
+  {"version": "go1.21.0", "stable": true, "files": [
+    {"filename": "go1.21.0.darwin-amd64.tar.gz", "sha256": "aaaa"},
+    {"filename": "go1.21.0.linux-amd64.tar.gz", "sha256": "aaaa1111"}
+  ]}
+]`)
+	})
+	target, _ := url.Parse(srv.URL)
+	return &http.Client{Transport: redirectTransport{target: target}}
+}
+
+func TestUpdater_Golang(t *testing.T) {
+	client := fakeGolangIndex(t)
+	for _, prefix := range golangURLPrefixes {
+		t.Run(prefix, func(t *testing.T) {
+			f := newFormulaFixture(t, fmt.Sprintf("class Go < Formula\n  url \"%s1.21.0.linux-amd64.tar.gz\"\n  sha256 \"aaaa1111\"\nend\n", prefix))
+			f.client = client
+			update := f.check()
+			require.NotNil(t, update)
+			assert.Equal(t, "1.21.1", update.Next)
+			assert.Equal(t, fmt.Sprintf("class Go < Formula\n  url \"%s1.21.1.linux-amd64.tar.gz\"\n  sha256 \"bbbb1111\"\nend\n", prefix), f.apply(update))
+		})
+	}
+}
+
+func TestListGolangVersions(t *testing.T) {
+	client := fakeGolangIndex(t)
+	versions, err := listGolangVersions(context.Background(), client, ChannelStable)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.21.1", "1.21.0"}, versions)
+
+	versions, err = listGolangVersions(context.Background(), client, ChannelRC)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.22rc1", "1.21.1", "1.21.0"}, versions)
+}
@@ -0,0 +1,170 @@
+package brew
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/thepwagner/action-update/updater"
+)
+
+const (
+	defaultHashiCorpURL = "https://releases.hashicorp.com"
+	// defaultHashiCorpKeyURL is the key that signs every product's SHA256SUMS.
+	defaultHashiCorpKeyURL = "https://www.hashicorp.com/.well-known/pgp-key.txt"
+)
+
+type hashicorpIndex struct {
+	Versions map[string]struct {
+		Shasums          string `json:"shasums"`
+		ShasumsSignature string `json:"shasums_signature"`
+	} `json:"versions"`
+}
+
+// hashicorpProduct identifies the product of a release download, like
+// https://releases.hashicorp.com/terraform/1.5.7/terraform_1.5.7_darwin_arm64.zip
+func (u Updater) hashicorpProduct(rawURL, version string) (string, bool) {
+	for _, base := range []string{u.hashicorpURL, defaultHashiCorpURL} {
+		base = strings.TrimSuffix(base, "/") + "/"
+		if !strings.HasPrefix(rawURL, base) {
+			continue
+		}
+		segments := strings.Split(strings.TrimPrefix(rawURL, base), "/")
+		if len(segments) != 3 || segments[1] != version || !strings.HasPrefix(segments[2], segments[0]+"_"+version+"_") {
+			return "", false
+		}
+		return segments[0], true
+	}
+	return "", false
+}
+
+// hashicorpSource discovers releases from the index of releases.hashicorp.com.
+type hashicorpSource struct{ u *Updater }
+
+func (s hashicorpSource) Match(dep updater.Dependency) bool {
+	_, ok := s.u.hashicorpProduct(expandVersion(dep.Path, dep.Version), dep.Version)
+	return ok
+}
+
+// ListVersions lists the product's versions. Variants with build metadata, like 1.2.3+ent, are only listed for
+// dependencies already on that variant.
+func (s hashicorpSource) ListVersions(ctx context.Context, q VersionQuery) ([]SourceVersion, error) {
+	product, _ := s.u.hashicorpProduct(expandVersion(q.Path, q.Version), q.Version)
+	index, err := s.u.fetchHashiCorpIndex(ctx, product)
+	if err != nil {
+		return nil, err
+	}
+
+	variant := hashicorpVariant(q.Version)
+	versions := make([]SourceVersion, 0, len(index.Versions))
+	for v := range index.Versions {
+		if hashicorpVariant(v) != variant {
+			continue
+		}
+		versions = append(versions, SourceVersion{Version: v})
+	}
+	logrus.WithFields(logrus.Fields{
+		"product":  product,
+		"versions": len(versions),
+	}).Debug("fetched hashicorp versions")
+	return versions, nil
+}
+
+func hashicorpVariant(version string) string {
+	if i := strings.Index(version, "+"); i >= 0 {
+		return version[i:]
+	}
+	return ""
+}
+
+// ResolveHash returns the hash SHA256SUMS lists for the next version's build, without downloading it. If GPG is
+// enabled, SHA256SUMS is verified against its signature first.
+func (s hashicorpSource) ResolveHash(ctx context.Context, update updater.Update, oldHash string) (string, error) {
+	if len(oldHash) != 64 {
+		return "", fmt.Errorf("hashicorp releases do not list a digest like %q", oldHash)
+	}
+	product, _ := s.u.hashicorpProduct(expandVersion(update.Path, update.Previous), update.Previous)
+	index, err := s.u.fetchHashiCorpIndex(ctx, product)
+	if err != nil {
+		return "", err
+	}
+	next := nextVersion(update)
+	release, ok := index.Versions[next]
+	if !ok || release.Shasums == "" {
+		return "", fmt.Errorf("hashicorp product %s has no SHA256SUMS for %s", product, next)
+	}
+
+	releaseURL := fmt.Sprintf("%s/%s/%s/", strings.TrimSuffix(s.u.hashicorpURL, "/"), product, next)
+	sums, err := fetchOptional(ctx, s.u.client, releaseURL+release.Shasums)
+	if err != nil {
+		return "", err
+	} else if sums == nil {
+		return "", fmt.Errorf("%s not found", releaseURL+release.Shasums)
+	}
+	if s.u.gpg {
+		if err := s.verifySums(ctx, sums, releaseURL+release.ShasumsSignature); err != nil {
+			return "", err
+		}
+	}
+
+	filename := path.Base(updatedTemplateURL(update.Path, update))
+	for _, line := range strings.Split(string(sums), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == filename {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("build %s not found in %s", filename, release.Shasums)
+}
+
+// verifySums verifies SHA256SUMS against its detached signature and HashiCorp's key. Every release is signed, so a
+// missing signature is an error.
+func (s hashicorpSource) verifySums(ctx context.Context, sums []byte, sigURL string) error {
+	sig, err := fetchOptional(ctx, s.u.client, sigURL)
+	if err != nil {
+		return err
+	} else if sig == nil {
+		return fmt.Errorf("signature %s not found", sigURL)
+	}
+	key, err := fetchOptional(ctx, s.u.client, s.u.hashicorpKeyURL)
+	if err != nil {
+		return err
+	} else if key == nil {
+		return fmt.Errorf("key %s not found", s.u.hashicorpKeyURL)
+	}
+
+	dir, err := ioutil.TempDir("", "signature-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	sumsFile := filepath.Join(dir, "SHA256SUMS")
+	if err := ioutil.WriteFile(sumsFile, sums, 0600); err != nil {
+		return err
+	}
+	if err := verifyWithKeyring(ctx, dir, key, sig, sumsFile); err != nil {
+		return err
+	}
+	logrus.WithField("signature", sigURL).Debug("verified signature")
+	return nil
+}
+
+func (u Updater) fetchHashiCorpIndex(ctx context.Context, product string) (*hashicorpIndex, error) {
+	indexURL := fmt.Sprintf("%s/%s/index.json", strings.TrimSuffix(u.hashicorpURL, "/"), product)
+	b, err := fetchOptional(ctx, u.client, indexURL)
+	if err != nil {
+		return nil, err
+	} else if b == nil {
+		return nil, fmt.Errorf("hashicorp product %s not found", product)
+	}
+	var index hashicorpIndex
+	if err := json.Unmarshal(b, &index); err != nil {
+		return nil, fmt.Errorf("decoding hashicorp index %s: %w", indexURL, err)
+	}
+	return &index, nil
+}
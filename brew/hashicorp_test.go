@@ -0,0 +1,99 @@
+package brew
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thepwagner/action-update/updater"
+)
+
+const hashicorpFormula = `class Terraform < Formula
+  version "%s"
+
+  on_macos do
+    url "%s/terraform/#{version}/terraform_#{version}_darwin_arm64.zip"
+    sha256 "%s"
+  end
+
+  on_linux do
+    url "%s/terraform/#{version}/terraform_#{version}_linux_amd64.zip"
+    sha256 "%s"
+  end
+end
+`
+
+func hashicorpSums(version string) string {
+	var sums strings.Builder
+	for _, build := range []string{"darwin_arm64", "linux_amd64"} {
+		filename := fmt.Sprintf("terraform_%s_%s.zip", version, build)
+		_, _ = fmt.Fprintf(&sums, "%s  %s\n", sha256Hex(filename), filename)
+	}
+	return sums.String()
+}
+
+// fakeHashiCorp serves the terraform index, with SHA256SUMS and their signatures.
+func fakeHashiCorp(t *testing.T, key string, sign func(string) string) *fakeServer {
+	srv := newFakeServer(t, map[string]string{"/key.txt": key})
+	index := map[string]interface{}{}
+	for _, v := range []string{"1.5.6", "1.5.7", "1.6.0-rc1", "1.5.8+ent"} {
+		sums := fmt.Sprintf("terraform_%s_SHA256SUMS", v)
+		index[v] = map[string]string{"shasums": sums, "shasums_signature": sums + ".sig"}
+		srv.file(fmt.Sprintf("/terraform/%s/%s", v, sums), hashicorpSums(v))
+		if sign != nil {
+			srv.file(fmt.Sprintf("/terraform/%s/%s.sig", v, sums), sign(hashicorpSums(v)))
+		}
+	}
+	srv.json("/terraform/index.json", map[string]interface{}{"name": "terraform", "versions": index})
+	return srv
+}
+
+func TestUpdater_HashiCorp(t *testing.T) {
+	srv := fakeHashiCorp(t, "", nil)
+
+	formula := func(version string) string {
+		sums := strings.Fields(hashicorpSums(version))
+		return fmt.Sprintf(hashicorpFormula, version, srv.URL, sums[0], srv.URL, sums[2])
+	}
+	f := newFormulaFixture(t, formula("1.5.6"), WithHashiCorp(srv.URL, ""))
+
+	// Release candidates and enterprise variants aren't proposed:
+	update := f.check()
+	require.NotNil(t, update)
+	assert.Equal(t, "1.5.7", update.Next)
+	assert.Equal(t, formula("1.5.7"), f.apply(update))
+}
+
+func TestUpdater_Update_HashiCorpSignature(t *testing.T) {
+	key, sign := newTestSigner(t)
+	cases := map[string]struct {
+		sign  func(string) string
+		valid bool
+	}{
+		"valid":    {sign: sign, valid: true},
+		"tampered": {sign: func(string) string { return sign("tampered") }, valid: false},
+		"unsigned": {sign: nil, valid: false},
+	}
+	for label, tc := range cases {
+		t.Run(label, func(t *testing.T) {
+			srv := fakeHashiCorp(t, key, tc.sign)
+			sums := strings.Fields(hashicorpSums("1.5.6"))
+			formula := fmt.Sprintf(hashicorpFormula, "1.5.6", srv.URL, sums[0], srv.URL, sums[2])
+			f := newFormulaFixture(t, formula, WithHashiCorp(srv.URL, srv.URL+"/key.txt"), WithGPG(true))
+			err := f.ApplyUpdate(context.Background(), updater.Update{
+				Path:     srv.URL + "/terraform/#{version}/terraform_#{version}_darwin_arm64.zip",
+				Previous: "1.5.6",
+				Next:     "1.5.7",
+			})
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Equal(t, formula, f.read())
+			}
+		})
+	}
+}
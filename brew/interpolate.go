@@ -0,0 +1,115 @@
+package brew
+
+import (
+	"regexp"
+	"strings"
+)
+
+// interpolationRe matches simple interpolations within a string: #{version}, #{VERSION}, #{version.csv.first}.
+var interpolationRe = regexp.MustCompile(`#{([A-Za-z_][A-Za-z0-9_]*)((?:\.[a-z_]+)*)}`)
+
+// expandVersion evaluates #{version} interpolations within a template, including the version methods of the formula
+// and cask DSLs (e.g. #{version.major_minor}, #{version.csv.second}).
+func expandVersion(template, version string) string {
+	return interpolationRe.ReplaceAllStringFunc(template, func(m string) string {
+		match := interpolationRe.FindStringSubmatch(m)
+		if !strings.EqualFold(match[1], "version") {
+			return m
+		}
+		var methods []string
+		if match[2] != "" {
+			methods = strings.Split(match[2][1:], ".")
+		}
+		if v, ok := versionMethods(version, methods); ok {
+			return v
+		}
+		return m
+	})
+}
+
+var ordinals = map[string]int{"first": 0, "second": 1, "third": 2, "fourth": 3, "fifth": 4}
+
+func versionMethods(version string, methods []string) (string, bool) {
+	var csv []string
+	for _, method := range methods {
+		if csv != nil {
+			i, ok := ordinals[method]
+			if !ok {
+				return "", false
+			}
+			if i < len(csv) {
+				version = csv[i]
+			} else {
+				version = ""
+			}
+			csv = nil
+			continue
+		}
+
+		switch method {
+		case "csv":
+			csv = strings.Split(version, ",")
+		case "before_comma":
+			version = strings.SplitN(version, ",", 2)[0]
+		case "after_comma":
+			split := strings.SplitN(version, ",", 2)
+			version = split[len(split)-1]
+		case "before_colon":
+			version = strings.SplitN(version, ":", 2)[0]
+		case "after_colon":
+			split := strings.SplitN(version, ":", 2)
+			version = split[len(split)-1]
+		case "major":
+			version = versionComponents(version, 1)
+		case "minor":
+			version = versionComponent(version, 1)
+		case "patch":
+			version = versionComponent(version, 2)
+		case "major_minor":
+			version = versionComponents(version, 2)
+		case "major_minor_patch":
+			version = versionComponents(version, 3)
+		case "no_dots":
+			version = strings.ReplaceAll(version, ".", "")
+		case "no_hyphens":
+			version = strings.ReplaceAll(version, "-", "")
+		case "dots_to_underscores":
+			version = strings.ReplaceAll(version, ".", "_")
+		case "dots_to_hyphens":
+			version = strings.ReplaceAll(version, ".", "-")
+		case "hyphens_to_dots":
+			version = strings.ReplaceAll(version, "-", ".")
+		case "underscores_to_dots":
+			version = strings.ReplaceAll(version, "_", ".")
+		case "to_s":
+		default:
+			return "", false
+		}
+	}
+	if csv != nil {
+		return "", false
+	}
+	return version, true
+}
+
+// versionComponents returns the first n dot-separated components of a version.
+func versionComponents(version string, n int) string {
+	split := strings.Split(version, ".")
+	if len(split) > n {
+		split = split[:n]
+	}
+	return strings.Join(split, ".")
+}
+
+func versionComponent(version string, i int) string {
+	split := strings.Split(version, ".")
+	if i < len(split) {
+		return split[i]
+	}
+	return ""
+}
+
+// csvFirst returns the first component of a comma separated cask version, e.g. "1.2.3" from "1.2.3,4567".
+func csvFirst(version string) string {
+	return strings.SplitN(version, ",", 2)[0]
+}
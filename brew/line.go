@@ -0,0 +1,76 @@
+package brew
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ReleaseLine selects which newer version of a dependency is proposed, relative to its current release line: the
+// versions sharing its major and minor components, e.g. 2.4.x.
+type ReleaseLine int
+
+const (
+	// ReleaseLineLatest proposes the newest version overall.
+	ReleaseLineLatest ReleaseLine = iota
+	// ReleaseLinePatch only proposes the newest version within the current release line.
+	ReleaseLinePatch
+	// ReleaseLinePatchFirst proposes the newest version within the current release line, and the newest version
+	// overall once the line is up to date.
+	ReleaseLinePatchFirst
+)
+
+var releaseLineNames = []string{"latest", "patch", "patch-first"}
+
+func (l ReleaseLine) String() string {
+	return releaseLineNames[l]
+}
+
+func parseReleaseLine(name string) (ReleaseLine, error) {
+	for i, n := range releaseLineNames {
+		if n == name {
+			return ReleaseLine(i), nil
+		}
+	}
+	return ReleaseLineLatest, fmt.Errorf("unknown release line %q, expected one of %s", name, strings.Join(releaseLineNames, ", "))
+}
+
+// nextVersion returns the version to propose from the candidates, or "" if there is none.
+func (l ReleaseLine) nextVersion(scheme VersionScheme, current string, candidates []string, filter func(string) bool) string {
+	if l == ReleaseLineLatest {
+		return latestVersion(scheme, current, candidates, filter)
+	}
+
+	line := releaseLineOf(current)
+	inLine := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if releaseLineOf(c) == line {
+			inLine = append(inLine, c)
+		}
+	}
+	if patch := latestVersion(scheme, current, inLine, filter); patch != "" || l == ReleaseLinePatch {
+		return patch
+	}
+	logrus.WithField("line", line).Debug("release line is up to date, checking latest version")
+	return latestVersion(scheme, current, candidates, filter)
+}
+
+// releaseLineOf returns the major and minor components of a version, e.g. "2.4" for "2.4.1".
+func releaseLineOf(version string) string {
+	m := filterVersionRe.FindStringSubmatch(csvFirst(version))
+	if m == nil {
+		return version
+	}
+	if m[2] == "" {
+		return trimZeros(m[1])
+	}
+	return trimZeros(m[1]) + "." + trimZeros(m[2])
+}
+
+func trimZeros(n string) string {
+	if n = strings.TrimLeft(n, "0"); n == "" {
+		return "0"
+	}
+	return n
+}
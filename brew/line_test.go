@@ -0,0 +1,70 @@
+package brew
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReleaseLineOf(t *testing.T) {
+	cases := map[string]string{
+		"2.4.1":      "2.4",
+		"v2.4":       "2.4",
+		"2.4.1-rc1":  "2.4",
+		"1.1.1w":     "1.1",
+		"2024.05.01": "2024.5",
+		"2.0.1":      "2.0",
+		"7":          "7",
+		"1.2.3,4567": "1.2",
+	}
+	for version, expected := range cases {
+		t.Run(version, func(t *testing.T) {
+			assert.Equal(t, expected, releaseLineOf(version))
+		})
+	}
+}
+
+func TestUpdater_Check_ReleaseLine(t *testing.T) {
+	gh := newFakeGitHub(t)
+	gh.json("/api/repos/foo/foo/releases", []*github.RepositoryRelease{
+		{TagName: github.String("v3.0.0")},
+		{TagName: github.String("v2.5.0")},
+		{TagName: github.String("v2.4.3")},
+		{TagName: github.String("v2.4.1")},
+	})
+
+	cases := map[string]struct {
+		line      ReleaseLine
+		directive string
+		version   string
+		next      string
+	}{
+		"latest":                 {next: "3.0.0"},
+		"patch":                  {line: ReleaseLinePatch, next: "2.4.3"},
+		"patch up to date":       {line: ReleaseLinePatch, version: "2.4.3"},
+		"patch-first":            {line: ReleaseLinePatchFirst, next: "2.4.3"},
+		"patch-first up to date": {line: ReleaseLinePatchFirst, version: "2.4.3", next: "3.0.0"},
+		"directive":              {directive: "# update: release-line=patch", next: "2.4.3"},
+		"directive latest":       {line: ReleaseLinePatch, directive: "# update: release-line=latest", next: "3.0.0"},
+	}
+	for label, tc := range cases {
+		t.Run(label, func(t *testing.T) {
+			version := tc.version
+			if version == "" {
+				version = "2.4.1"
+			}
+			f := newFormulaFixture(t, fmt.Sprintf("class Foo < Formula\n  %s\n  url \"https://github.com/foo/foo/releases/download/v%s/foo.tar.gz\"\nend\n", tc.directive, version), WithReleaseLine(tc.line))
+			f.ghRepos = gh.client.Repositories
+			update := f.check()
+			if tc.next == "" {
+				assert.Nil(t, update)
+				return
+			}
+			require.NotNil(t, update)
+			assert.Equal(t, tc.next, update.Next)
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package brew
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// livecheck is a formula's instructions for discovering new versions:
+//
+//	livecheck do
+//	  url :stable
+//	  regex(/href=.*?foo[._-]v?(\d+(?:\.\d+)+)\.t/i)
+//	end
+type livecheck struct {
+	url      string
+	regex    *regexp.Regexp
+	strategy string
+	// skip is the reason the formula opted out of version checks.
+	skip string
+}
+
+// Default regular expressions, mirroring Homebrew's strategies:
+var (
+	githubLatestRe = regexp.MustCompile(`(?i)v?(\d+(?:\.\d+)+)`)
+	gitTagRe       = regexp.MustCompile(`(?i)^v?(\d+(?:\.\d+)+)$`)
+)
+
+// parseLivecheck interprets the livecheck block of a dependency, returning nil if there is none.
+func parseLivecheck(dep *formulaDep) (*livecheck, error) {
+	blocks := dep.scope.Children("livecheck")
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+	block := blocks[0]
+
+	lc := &livecheck{url: expandVersion(dep.Path, dep.Version)}
+	for _, s := range block.Stanzas {
+		arg, ok := s.Arg()
+		switch s.Method {
+		case "skip":
+			lc.skip = "skipped"
+			if ok {
+				lc.skip = arg.Value
+			}
+		case "url":
+			if !ok {
+				continue
+			}
+			u, err := livecheckURL(dep, arg)
+			if err != nil {
+				return nil, err
+			}
+			lc.url = u
+		case "regex":
+			if !ok || !arg.Regexp {
+				return nil, fmt.Errorf("livecheck line %d: regex must be a regular expression literal", s.Line)
+			}
+			re, err := rubyRegexp(arg)
+			if err != nil {
+				return nil, fmt.Errorf("livecheck line %d: %w", s.Line, err)
+			}
+			lc.regex = re
+		case "strategy":
+			if !ok || !arg.Symbol {
+				return nil, fmt.Errorf("livecheck line %d: strategy must be a symbol", s.Line)
+			}
+			lc.strategy = arg.Value
+			if len(block.Children("strategy")) > 0 {
+				logrus.WithField("strategy", lc.strategy).Debug("ignoring livecheck strategy block, matching regex instead")
+			}
+		default:
+			return nil, fmt.Errorf("livecheck line %d: unsupported stanza %q", s.Line, s.Method)
+		}
+	}
+	return lc, nil
+}
+
+// livecheckURL resolves a livecheck url, which may refer to another url stanza of the formula.
+func livecheckURL(dep *formulaDep, arg Literal) (string, error) {
+	if !arg.Symbol {
+		u := expandVersion(arg.Value, dep.Version)
+		if m := interpolationRe.FindStringSubmatch(u); m != nil {
+			return "", fmt.Errorf("livecheck url interpolates unsupported %q", m[0])
+		}
+		return u, nil
+	}
+
+	switch arg.Value {
+	case "stable", "url":
+		return expandVersion(dep.Path, dep.Version), nil
+	case "homepage", "head":
+		main := dep.scope
+		for main.Parent != nil && main.Kind != "class" && main.Kind != "cask" {
+			main = main.Parent
+		}
+		if u, ok := main.First(arg.Value).Arg(); ok {
+			return expandVersion(u.Value, dep.Version), nil
+		}
+		return "", fmt.Errorf("livecheck url :%s is not declared", arg.Value)
+	default:
+		return "", fmt.Errorf("unsupported livecheck url :%s", arg.Value)
+	}
+}
+
+// rubyRegexp translates a Ruby regular expression literal to Go.
+func rubyRegexp(lit Literal) (*regexp.Regexp, error) {
+	if strings.Contains(lit.Value, "#{") {
+		return nil, fmt.Errorf("regex %q interpolates ruby code", lit.Value)
+	}
+
+	pattern := strings.NewReplacer(
+		`(?<`, `(?P<`,
+		`\h`, `[0-9a-fA-F]`,
+		`\Z`, `\z`,
+		`\/`, `/`,
+	).Replace(lit.Value)
+	// Lookbehind is written (?<= and (?<!, which Go doesn't support either:
+	pattern = strings.NewReplacer(`(?P<=`, `(?<=`, `(?P<!`, `(?<!`).Replace(pattern)
+
+	// Ruby's ^ and $ always match at line boundaries:
+	flags := "m"
+	for _, f := range lit.Flags {
+		switch f {
+		case 'i':
+			flags += "i"
+		case 'm':
+			// Ruby's multiline mode lets . match newlines:
+			flags += "s"
+		case 'x':
+			return nil, fmt.Errorf("regex %q uses unsupported extended mode", lit.Value)
+		}
+	}
+	pattern = fmt.Sprintf("(?%s)%s", flags, pattern)
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("translating regex %q: %w", lit.Value, err)
+	}
+	return re, nil
+}
+
+// inferred returns true if the livecheck names neither a strategy nor a regex. Homebrew infers the strategy from the
+// url, as the dependency's source does.
+func (lc *livecheck) inferred() bool {
+	return lc.skip == "" && lc.strategy == "" && lc.regex == nil
+}
+
+// livecheckVersions lists the versions found by a livecheck. Skipped livechecks find no versions.
+func (u Updater) livecheckVersions(ctx context.Context, dep *formulaDep, lc *livecheck) ([]string, error) {
+	log := logrus.WithFields(logrus.Fields{
+		"path":     dep.Path,
+		"url":      lc.url,
+		"strategy": lc.strategy,
+	})
+	if lc.skip != "" {
+		log.WithField("reason", lc.skip).Info("livecheck skipped")
+		return nil, nil
+	}
+
+	versions, err := u.livecheckStrategy(ctx, dep, lc)
+	if err != nil {
+		return nil, fmt.Errorf("livecheck %s: %w", lc.url, err)
+	}
+	log.WithField("versions", len(versions)).Debug("fetched livecheck versions")
+	return versions, nil
+}
+
+func (u Updater) livecheckStrategy(ctx context.Context, dep *formulaDep, lc *livecheck) ([]string, error) {
+	switch lc.strategy {
+	case "page_match", "":
+		if lc.regex == nil {
+			return nil, fmt.Errorf("page_match requires a regex")
+		}
+		body, err := u.livecheckGet(ctx, lc.url)
+		if err != nil {
+			return nil, err
+		}
+		return matchVersions(dep, lc.regex, string(body)), nil
+
+	case "github_latest":
+		owner, name, err := parseGitHubRelease(lc.url)
+		if err != nil {
+			return nil, fmt.Errorf("github_latest: %w", err)
+		}
+		release, _, err := u.ghRepos.GetLatestRelease(ctx, owner, name)
+		if err != nil {
+			return nil, fmt.Errorf("querying latest release: %w", err)
+		}
+		re := lc.regex
+		if re == nil {
+			re = githubLatestRe
+		}
+		return matchVersions(dep, re, release.GetTagName()), nil
+
+	case "git":
+		tags, err := lsRemoteTags(ctx, lc.url)
+		if err != nil {
+			return nil, err
+		}
+		re := lc.regex
+		if re == nil {
+			re = gitTagRe
+		}
+		var versions []string
+		for tag := range tags {
+			versions = append(versions, matchVersions(dep, re, tag)...)
+		}
+		return versions, nil
+
+	case "header_match":
+		return u.headerMatchVersions(ctx, dep, lc)
+
+	case "json":
+		if lc.regex == nil {
+			return nil, fmt.Errorf("json requires a regex")
+		}
+		body, err := u.livecheckGet(ctx, lc.url)
+		if err != nil {
+			return nil, err
+		}
+		var doc interface{}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return nil, fmt.Errorf("decoding json: %w", err)
+		}
+		var versions []string
+		for _, s := range jsonStrings(doc) {
+			versions = append(versions, matchVersions(dep, lc.regex, s)...)
+		}
+		return versions, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported livecheck strategy :%s", lc.strategy)
+	}
+}
+
+func (u Updater) livecheckGet(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := u.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// headerMatchVersions matches the regex against the url a request is redirected to, and any attachment filename.
+func (u Updater) headerMatchVersions(ctx context.Context, dep *formulaDep, lc *livecheck) ([]string, error) {
+	req, err := http.NewRequest("HEAD", lc.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := u.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	candidates := []string{res.Request.URL.String()}
+	if _, params, err := mime.ParseMediaType(res.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		candidates = append(candidates, params["filename"])
+	}
+
+	var versions []string
+	for _, c := range candidates {
+		if lc.regex != nil {
+			versions = append(versions, matchVersions(dep, lc.regex, c)...)
+		} else if v := urlVersion(path.Base(c)); v != "" {
+			versions = append(versions, v)
+		}
+	}
+	return versions, nil
+}
+
+// matchVersions extracts versions from every match of the regex: the first capture group, or the whole match.
+// Versions with a build number, like casks' "1.2.3,4567", are formed by joining every capture group.
+func matchVersions(dep *formulaDep, re *regexp.Regexp, s string) []string {
+	var versions []string
+	for _, m := range re.FindAllStringSubmatch(s, -1) {
+		switch {
+		case len(m) > 2 && strings.Contains(dep.Version, ","):
+			var parts []string
+			for _, g := range m[1:] {
+				if g != "" {
+					parts = append(parts, g)
+				}
+			}
+			versions = append(versions, strings.Join(parts, ","))
+		case len(m) > 1:
+			versions = append(versions, m[1])
+		default:
+			versions = append(versions, m[0])
+		}
+	}
+	return versions
+}
+
+// jsonStrings returns every string value within a decoded JSON document.
+func jsonStrings(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var ret []string
+		for _, e := range v {
+			ret = append(ret, jsonStrings(e)...)
+		}
+		return ret
+	case map[string]interface{}:
+		var ret []string
+		for _, e := range v {
+			ret = append(ret, jsonStrings(e)...)
+		}
+		return ret
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,152 @@
+package brew
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const livecheckFormula = `class Foo < Formula
+  homepage "%[1]s/"
+  url "%[1]s/foo-1.0.0.tar.gz"
+  sha256 "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+
+  livecheck do
+%[2]s
+  end
+end
+`
+
+func livecheckFixture(t *testing.T, gh *fakeGitHub, livecheck string) *formulaFixture {
+	f := newFormulaFixture(t, fmt.Sprintf(livecheckFormula, gh.URL, livecheck))
+	f.ghRepos = gh.client.Repositories
+	return f
+}
+
+func TestUpdater_Check_Livecheck(t *testing.T) {
+	gh := newFakeGitHub(t)
+	gh.file("/downloads", `<a href="foo-1.0.0.tar.gz">foo-1.0.0.tar.gz</a>
+<a href="foo-1.10.0.tar.gz">foo-1.10.0.tar.gz</a>
+<a href="foo-1.9.0.tar.gz">foo-1.9.0.tar.gz</a>
+<a href="foo-2.0.0-rc1.zip">foo-2.0.0-rc1.zip</a>`)
+	// The homepage only links the current version:
+	gh.file("/", `<a href="foo-1.0.0.tar.gz">Download</a>`)
+	gh.mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/foo-1.4.0.tar.gz", http.StatusFound)
+	})
+	gh.file("/foo-1.4.0.tar.gz", "foo")
+	gh.json("/versions.json", map[string]interface{}{
+		"releases": []map[string]string{{"version": "1.2.0"}, {"version": "1.3.0"}, {"version": "nightly"}},
+	})
+	gh.json("/api/repos/foo/foo/releases/latest", &github.RepositoryRelease{TagName: github.String("v1.5.0")})
+
+	cases := map[string]struct {
+		livecheck string
+		next      string
+	}{
+		"page_match": {
+			livecheck: fmt.Sprintf(`    url "%s/downloads"
+    regex(/href=.*?foo[._-]v?(\d+(?:\.\d+)+)\.t/i)`, gh.URL),
+			next: "1.10.0",
+		},
+		"page_match homepage": {
+			livecheck: `    url :homepage
+    regex(%r{href=.*?foo-(\d+(?:\.\d+)+)\.t}i)`,
+		},
+		"header_match": {
+			livecheck: fmt.Sprintf(`    url "%s/latest"
+    strategy :header_match`, gh.URL),
+			next: "1.4.0",
+		},
+		"json": {
+			livecheck: fmt.Sprintf(`    url "%s/versions.json"
+    strategy :json
+    regex(/^(?<version>\d+(?:\.\d+)+)$/)`, gh.URL),
+			next: "1.3.0",
+		},
+		"github_latest": {
+			livecheck: `    url "https://github.com/foo/foo/releases/latest"
+    strategy :github_latest`,
+			next: "1.5.0",
+		},
+		"skip": {
+			livecheck: `    skip "No version information available"`,
+		},
+	}
+	for label, tc := range cases {
+		t.Run(label, func(t *testing.T) {
+			update := livecheckFixture(t, gh, tc.livecheck).check()
+			if tc.next == "" {
+				assert.Nil(t, update)
+				return
+			}
+			require.NotNil(t, update)
+			assert.Equal(t, "1.0.0", update.Previous)
+			assert.Equal(t, tc.next, update.Next)
+		})
+	}
+}
+
+func TestUpdater_Check_LivecheckGitHubLatestNotGitHub(t *testing.T) {
+	gh := newFakeGitHub(t)
+	f := livecheckFixture(t, gh, `    url :homepage
+    strategy :github_latest`)
+	_, err := f.Check(context.Background(), f.deps(1)[0], nil)
+	assert.Error(t, err)
+}
+
+func TestUpdater_Check_LivecheckInferred(t *testing.T) {
+	gh := newFakeGitHub(t)
+	gh.file("/", `<a href="foo-1.0.0.tar.gz">foo-1.0.0.tar.gz</a> <a href="foo-1.1.0.tar.gz">foo-1.1.0.tar.gz</a>`)
+
+	// Without a strategy or regex, versions are discovered from the url's source:
+	update := livecheckFixture(t, gh, `    url :stable`).check()
+	require.NotNil(t, update)
+	assert.Equal(t, "1.1.0", update.Next)
+}
+
+func TestUpdater_Check_LivecheckGit(t *testing.T) {
+	repo, _ := gitRepo(t, "v1.0.0", "v1.2.0", "v1.1.0", "nightly")
+
+	gh := newFakeGitHub(t)
+	update := livecheckFixture(t, gh, fmt.Sprintf(`    url %q
+    strategy :git`, repo)).check()
+	require.NotNil(t, update)
+	assert.Equal(t, "1.2.0", update.Next)
+}
+
+func TestRubyRegexp(t *testing.T) {
+	cases := []struct {
+		lit      Literal
+		input    string
+		expected string
+	}{
+		{lit: Literal{Value: `foo-(\d+(?:\.\d+)+)\.t`}, input: "foo-1.2.3.tar.gz", expected: "1.2.3"},
+		{lit: Literal{Value: `FOO-(\d+)`, Flags: "i"}, input: "foo-12", expected: "12"},
+		{lit: Literal{Value: `href=.*?\/v?(?<version>\d+(?:\.\d+)+)\/`}, input: `href="/v1.2/"`, expected: "1.2"},
+		{lit: Literal{Value: `id=(\h+)`}, input: "id=deadBEEF", expected: "deadBEEF"},
+		{lit: Literal{Value: `a.(b)`, Flags: "m"}, input: "a\nb", expected: "b"},
+		{lit: Literal{Value: `^v(\d+)$`}, input: "foo\nv12\nbar", expected: "12"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.lit.Value, func(t *testing.T) {
+			re, err := rubyRegexp(tc.lit)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, re.FindStringSubmatch(tc.input)[1])
+		})
+	}
+
+	for _, lit := range []Literal{
+		{Value: `foo-(?=\d)`},
+		{Value: `#{Regexp.escape(name)}-(\d+)`},
+		{Value: `foo # comment`, Flags: "x"},
+	} {
+		_, err := rubyRegexp(lit)
+		assert.Error(t, err, lit.Value)
+	}
+}
@@ -0,0 +1,173 @@
+package brew
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/thepwagner/action-update/updater"
+)
+
+const defaultMavenURL = "https://repo1.maven.org/maven2"
+
+// mavenCentralURLs are the hosts of Maven Central, which are always checked as Maven repositories.
+var mavenCentralURLs = []string{defaultMavenURL, "https://repo.maven.apache.org/maven2"}
+
+type mavenMetadata struct {
+	Versions []string `xml:"versioning>versions>version"`
+}
+
+// mavenArtifact is a file in a Maven repository, e.g. https://repo1.maven.org/maven2/org/foo/foo-cli/1.2.3/foo-cli-1.2.3-bin.tar.gz
+type mavenArtifact struct {
+	// metadata is the url of the artifact's maven-metadata.xml, listing every version.
+	metadata string
+}
+
+// mavenArtifact identifies a file in the configured Maven repository or Maven Central.
+func (u Updater) mavenArtifact(rawURL, version string) (*mavenArtifact, bool) {
+	for _, repo := range append([]string{u.mavenURL}, mavenCentralURLs...) {
+		repo = strings.TrimSuffix(repo, "/") + "/"
+		if !strings.HasPrefix(rawURL, repo) {
+			continue
+		}
+		// Files are stored as group/path/artifact/version/artifact-version[-classifier].ext:
+		segments := strings.Split(strings.TrimPrefix(rawURL, repo), "/")
+		n := len(segments)
+		if n < 4 || segments[n-2] != version || !strings.HasPrefix(segments[n-1], segments[n-3]+"-"+version) {
+			return nil, false
+		}
+		return &mavenArtifact{metadata: repo + path.Join(segments[:n-2]...) + "/maven-metadata.xml"}, true
+	}
+	return nil, false
+}
+
+// mavenSource discovers artifacts published to Maven Central, or another Maven repository.
+type mavenSource struct{ u *Updater }
+
+func (s mavenSource) Match(dep updater.Dependency) bool {
+	_, ok := s.u.mavenArtifact(expandVersion(dep.Path, dep.Version), dep.Version)
+	return ok
+}
+
+func (s mavenSource) ListVersions(ctx context.Context, q VersionQuery) ([]SourceVersion, error) {
+	a, _ := s.u.mavenArtifact(expandVersion(q.Path, q.Version), q.Version)
+	b, err := fetchOptional(ctx, s.u.client, a.metadata)
+	if err != nil {
+		return nil, err
+	} else if b == nil {
+		return nil, fmt.Errorf("maven metadata %s not found", a.metadata)
+	}
+	var metadata mavenMetadata
+	if err := xml.Unmarshal(b, &metadata); err != nil {
+		return nil, fmt.Errorf("decoding maven metadata %s: %w", a.metadata, err)
+	}
+
+	versions := make([]SourceVersion, 0, len(metadata.Versions))
+	for _, v := range metadata.Versions {
+		if strings.HasSuffix(v, "-SNAPSHOT") {
+			continue
+		}
+		versions = append(versions, SourceVersion{Version: v})
+	}
+	logrus.WithFields(logrus.Fields{
+		"metadata": a.metadata,
+		"versions": len(versions),
+	}).Debug("fetched maven versions")
+	return versions, nil
+}
+
+// mavenChecksums are the sidecar files Maven publishes beside artifacts, by the length of the hex digest.
+var mavenChecksums = map[int]string{
+	128: ".sha512",
+	64:  ".sha256",
+	40:  ".sha1",
+}
+
+var mavenChecksumRe = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+
+// ResolveHash returns the hash of the next version's artifact from its checksum sidecar, downloading the artifact if
+// there's no sidecar using the formula's algorithm. If GPG is enabled, the artifact is downloaded to verify its .asc
+// signature, and must match the sidecar.
+func (s mavenSource) ResolveHash(ctx context.Context, update updater.Update, oldHash string) (string, error) {
+	h, ok := hasher(oldHash)
+	if !ok {
+		return "", nil
+	}
+	newURL := updatedTemplateURL(update.Path, update)
+
+	var checksum string
+	if ext, ok := mavenChecksums[len(oldHash)]; ok {
+		b, err := fetchOptional(ctx, s.u.client, newURL+ext)
+		if err != nil {
+			return "", err
+		}
+		// Sidecars hold the digest, sometimes followed by the filename:
+		if fields := strings.Fields(string(b)); len(fields) > 0 && len(fields[0]) == len(oldHash) && mavenChecksumRe.MatchString(fields[0]) {
+			checksum = strings.ToLower(fields[0])
+		}
+	}
+	if checksum != "" && !s.u.gpg {
+		return checksum, nil
+	}
+
+	res, err := getAsset(ctx, s.u.client, newURL)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	dir, err := ioutil.TempDir("", "signature-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	artifactFile := filepath.Join(dir, path.Base(newURL))
+	f, err := os.OpenFile(artifactFile, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(io.MultiWriter(h, f), res.Body)
+	f.Close()
+	if err != nil {
+		return "", err
+	}
+	newHash := fmt.Sprintf("%x", h.Sum(nil))
+	if checksum != "" && checksum != newHash {
+		return "", fmt.Errorf("%s does not match its checksum %s", newURL, checksum)
+	}
+
+	if s.u.gpg {
+		if err := s.verifySignature(ctx, artifactFile, newURL+".asc"); err != nil {
+			return "", err
+		}
+	}
+	return newHash, nil
+}
+
+// verifySignature verifies a downloaded artifact against its .asc signature, using keys already trusted by GPG.
+// Maven Central requires signatures, so a missing signature is an error.
+func (s mavenSource) verifySignature(ctx context.Context, artifactFile, sigURL string) error {
+	sig, err := fetchOptional(ctx, s.u.client, sigURL)
+	if err != nil {
+		return err
+	} else if sig == nil {
+		return fmt.Errorf("signature %s not found", sigURL)
+	}
+	sigFile := artifactFile + ".asc"
+	if err := ioutil.WriteFile(sigFile, sig, 0600); err != nil {
+		return err
+	}
+	if out, err := exec.CommandContext(ctx, "gpg", "--batch", "--verify", sigFile, artifactFile).CombinedOutput(); err != nil {
+		return fmt.Errorf("gpg --verify: %w: %s", err, out)
+	}
+	logrus.WithField("signature", sigURL).Debug("verified signature")
+	return nil
+}
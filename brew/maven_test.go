@@ -0,0 +1,101 @@
+package brew
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thepwagner/action-update/updater"
+)
+
+const mavenMetadataXML = `<?xml version="1.0" encoding="UTF-8"?>
+<metadata>
+  <groupId>org.example</groupId>
+  <artifactId>foo-cli</artifactId>
+  <versioning>
+    <latest>1.3.0-SNAPSHOT</latest>
+    <release>1.2.0</release>
+    <versions>
+      <version>1.0.0</version>
+      <version>1.1.0</version>
+      <version>1.2.0</version>
+      <version>1.3.0-SNAPSHOT</version>
+    </versions>
+  </versioning>
+</metadata>
+`
+
+func TestUpdater_Maven(t *testing.T) {
+	cases := map[string]struct {
+		files map[string]string
+		hash  string
+	}{
+		// The artifact isn't downloaded if the sidecar matches the formula's algorithm:
+		"sidecar": {
+			files: map[string]string{
+				"1.2.0/foo-cli-1.2.0-bin.tar.gz.sha256": strings.Repeat("B", 64) + "  foo-cli-1.2.0-bin.tar.gz\n",
+			},
+			hash: strings.Repeat("b", 64),
+		},
+		"download": {
+			files: map[string]string{
+				"1.2.0/foo-cli-1.2.0-bin.tar.gz":      "foo 1.2.0",
+				"1.2.0/foo-cli-1.2.0-bin.tar.gz.sha1": strings.Repeat("c", 40),
+			},
+			hash: sha256Hex("foo 1.2.0"),
+		},
+	}
+	for label, tc := range cases {
+		t.Run(label, func(t *testing.T) {
+			srv := newFakeServer(t, map[string]string{"/repo/org/example/foo-cli/maven-metadata.xml": mavenMetadataXML})
+			for p, contents := range tc.files {
+				srv.file("/repo/org/example/foo-cli/"+p, contents)
+			}
+
+			const formula = "class Foo < Formula\n  url \"%s/repo/org/example/foo-cli/%[2]s/foo-cli-%[2]s-bin.tar.gz\"\n  sha256 \"%s\"\nend\n"
+			f := newFormulaFixture(t, fmt.Sprintf(formula, srv.URL, "1.0.0", strings.Repeat("a", 64)), WithMaven(srv.URL+"/repo"))
+			update := f.check()
+			require.NotNil(t, update)
+			assert.Equal(t, "1.2.0", update.Next)
+			assert.Equal(t, fmt.Sprintf(formula, srv.URL, "1.2.0", tc.hash), f.apply(update))
+		})
+	}
+}
+
+func TestUpdater_MavenMissingSignature(t *testing.T) {
+	srv := newFakeServer(t, map[string]string{
+		"/repo/org/example/foo-cli/maven-metadata.xml":                    mavenMetadataXML,
+		"/repo/org/example/foo-cli/1.2.0/foo-cli-1.2.0-bin.tar.gz":        "foo 1.2.0",
+		"/repo/org/example/foo-cli/1.2.0/foo-cli-1.2.0-bin.tar.gz.sha256": sha256Hex("foo 1.2.0"),
+	})
+	const formula = "class Foo < Formula\n  url \"%s/repo/org/example/foo-cli/1.0.0/foo-cli-1.0.0-bin.tar.gz\"\n  sha256 \"%s\"\nend\n"
+	original := fmt.Sprintf(formula, srv.URL, strings.Repeat("a", 64))
+	f := newFormulaFixture(t, original, WithMaven(srv.URL+"/repo"), WithGPG(true))
+
+	err := f.ApplyUpdate(context.Background(), updater.Update{Path: srv.URL + "/repo/org/example/foo-cli/1.0.0/foo-cli-1.0.0-bin.tar.gz", Previous: "1.0.0", Next: "1.2.0"})
+	assert.Error(t, err)
+	assert.Equal(t, original, f.read())
+}
+
+func TestUpdater_MavenArtifact(t *testing.T) {
+	u := NewUpdater("")
+	cases := map[string]string{
+		"https://repo1.maven.org/maven2/org/example/foo-cli/1.2.3/foo-cli-1.2.3.jar":           "https://repo1.maven.org/maven2/org/example/foo-cli/maven-metadata.xml",
+		"https://repo.maven.apache.org/maven2/org/example/foo-cli/1.2.3/foo-cli-1.2.3-bin.zip": "https://repo.maven.apache.org/maven2/org/example/foo-cli/maven-metadata.xml",
+		"https://repo1.maven.org/maven2/org/example/foo-cli/1.2.3/bar-1.2.3.jar":               "",
+		"https://repo1.maven.org/maven2/org/example/foo-cli/foo-cli-1.2.3.jar":                 "",
+		"https://example.com/maven2/org/example/foo-cli/1.2.3/foo-cli-1.2.3.jar":               "",
+	}
+	for rawURL, expected := range cases {
+		t.Run(rawURL, func(t *testing.T) {
+			a, ok := u.mavenArtifact(rawURL, "1.2.3")
+			assert.Equal(t, expected != "", ok)
+			if ok {
+				assert.Equal(t, expected, a.metadata)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package brew
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/thepwagner/action-update/updater"
+)
+
+// scopeMirrors returns the mirror urls declared directly within a block.
+func scopeMirrors(scope *Block) []Literal {
+	var mirrors []Literal
+	for _, s := range scope.Find("mirror") {
+		if arg, ok := s.Arg(); ok && !arg.Symbol {
+			mirrors = append(mirrors, arg)
+		}
+	}
+	return mirrors
+}
+
+// verifyMirrors checks the updated mirrors of an artifact serve the same file as its updated url.
+func (u Updater) verifyMirrors(ctx context.Context, a *artifact, update updater.Update, newHash string) error {
+	for _, mirror := range a.mirrors {
+		mirrorURL, resolved, err := u.resolvedURL(ctx, mirror.Value, update)
+		if err != nil {
+			return fmt.Errorf("resolving mirror %s: %w", mirror.Value, err)
+		} else if !resolved {
+			mirrorURL = updatedTemplateURL(mirror.Value, update)
+		}
+		logrus.WithFields(logrus.Fields{
+			"mirror": mirrorURL,
+			"hash":   newHash,
+		}).Debug("verifying updated mirror")
+
+		ok, err := isHashAsset(ctx, u.client, mirrorURL, newHash)
+		if err != nil {
+			return fmt.Errorf("verifying mirror %s: %w", mirrorURL, err)
+		}
+		if !ok {
+			return fmt.Errorf("mirror %s does not serve %s", mirrorURL, newHash)
+		}
+	}
+	return nil
+}
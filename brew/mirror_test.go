@@ -0,0 +1,67 @@
+package brew
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thepwagner/action-update/updater"
+)
+
+const mirrorFormula = `class Foo < Formula
+  url "%[1]s/foo-1.0.0.tar.gz"
+  mirror "%[1]s/mirror/foo-1.0.0.tar.gz"
+  sha256 "%[2]s"
+
+  patch do
+    url "%[1]s/patches/foo-fix.patch"
+    sha256 "%[3]s"
+  end
+end
+`
+
+func TestUpdater_Update_Mirror(t *testing.T) {
+	srv := newFakeServer(t, map[string]string{
+		"/foo-1.0.0.tar.gz":        "foo 1.0.0",
+		"/foo-1.1.0.tar.gz":        "foo 1.1.0",
+		"/mirror/foo-1.1.0.tar.gz": "foo 1.1.0",
+	})
+	f := newFormulaFixture(t, fmt.Sprintf(mirrorFormula, srv.URL, sha256Hex("foo 1.0.0"), sha256Hex("patch")))
+	formula := f.apply(&updater.Update{Path: srv.URL + "/foo-1.0.0.tar.gz", Previous: "1.0.0", Next: "1.1.0"})
+
+	assert.Contains(t, formula, srv.URL+"/mirror/foo-1.1.0.tar.gz")
+	assert.Contains(t, formula, sha256Hex("foo 1.1.0"))
+	assert.Contains(t, formula, srv.URL+"/patches/foo-fix.patch")
+	assert.Contains(t, formula, sha256Hex("patch"))
+}
+
+func TestUpdater_Update_MirrorMismatch(t *testing.T) {
+	srv := newFakeServer(t, map[string]string{
+		"/foo-1.0.0.tar.gz":        "foo 1.0.0",
+		"/foo-1.1.0.tar.gz":        "foo 1.1.0",
+		"/mirror/foo-1.1.0.tar.gz": "tampered",
+	})
+	original := fmt.Sprintf(mirrorFormula, srv.URL, sha256Hex("foo 1.0.0"), sha256Hex("patch"))
+	f := newFormulaFixture(t, original)
+
+	err := f.ApplyUpdate(context.Background(), updater.Update{Path: srv.URL + "/foo-1.0.0.tar.gz", Previous: "1.0.0", Next: "1.1.0"})
+	assert.Error(t, err)
+	assert.Equal(t, original, f.read())
+}
+
+func TestUpdater_Update_VersionedPatch(t *testing.T) {
+	srv := newFakeServer(t, map[string]string{
+		"/foo-1.0.0.tar.gz":        "foo 1.0.0",
+		"/foo-1.1.0.tar.gz":        "foo 1.1.0",
+		"/mirror/foo-1.1.0.tar.gz": "foo 1.1.0",
+	})
+	original := strings.Replace(fmt.Sprintf(mirrorFormula, srv.URL, sha256Hex("foo 1.0.0"), sha256Hex("patch")), "foo-fix.patch", "foo-1.0.0-fix.patch", 1)
+	f := newFormulaFixture(t, original)
+
+	// Patches for the previous version are left for review:
+	err := f.ApplyUpdate(context.Background(), updater.Update{Path: srv.URL + "/foo-1.0.0.tar.gz", Previous: "1.0.0", Next: "1.1.0"})
+	assert.Error(t, err)
+	assert.Equal(t, original, f.read())
+}
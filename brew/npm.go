@@ -0,0 +1,171 @@
+package brew
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/thepwagner/action-update/updater"
+)
+
+const defaultNPMURL = "https://registry.npmjs.org"
+
+// npmPackument is a package document from the registry.
+type npmPackument struct {
+	DistTags map[string]string `json:"dist-tags"`
+	Versions map[string]struct {
+		Dist struct {
+			Tarball   string `json:"tarball"`
+			Shasum    string `json:"shasum"`
+			Integrity string `json:"integrity"`
+		} `json:"dist"`
+	} `json:"versions"`
+	Time map[string]time.Time `json:"time"`
+}
+
+// npmTarballRe matches the path of a registry tarball, e.g. /@scope/pkg/-/pkg-1.2.3.tgz
+var npmTarballRe = regexp.MustCompile(`^/((?:@[^/]+/)?[^/]+)/-/[^/]+\.tgz$`)
+
+// npmPackage identifies the package of a registry tarball url.
+func (u Updater) npmPackage(rawURL string) (string, bool) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	base, err := url.Parse(u.npmURL)
+	if err != nil {
+		return "", false
+	}
+	if parsed.Host != "registry.npmjs.org" && parsed.Host != base.Host {
+		return "", false
+	}
+	m := npmTarballRe.FindStringSubmatch(parsed.Path)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// npmSource discovers packages published to the npm registry.
+type npmSource struct{ u *Updater }
+
+func (s npmSource) Match(dep updater.Dependency) bool {
+	_, ok := s.u.npmPackage(expandVersion(dep.Path, dep.Version))
+	return ok
+}
+
+func (s npmSource) ListVersions(ctx context.Context, q VersionQuery) ([]SourceVersion, error) {
+	name, _ := s.u.npmPackage(expandVersion(q.Path, q.Version))
+	dates := versionDates{}
+	versions, err := s.u.listNPMVersions(ctx, name, q.Scheme, q.Channel, dates)
+	if err != nil {
+		return nil, err
+	}
+	return sourceVersions(versions, dates), nil
+}
+
+func (s npmSource) ResolveHash(ctx context.Context, update updater.Update, oldHash string) (string, error) {
+	return s.u.updatedNPMHash(ctx, update, oldHash)
+}
+
+func (u Updater) fetchNPMPackument(ctx context.Context, name string) (*npmPackument, error) {
+	// Scoped packages are fetched as @scope%2fpkg:
+	req, err := http.NewRequest("GET", strings.TrimSuffix(u.npmURL, "/")+"/"+strings.Replace(name, "/", "%2f", 1), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := u.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching npm package %s: unexpected status %d", name, res.StatusCode)
+	}
+
+	var doc npmPackument
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decoding npm package %s: %w", name, err)
+	}
+	return &doc, nil
+}
+
+// listNPMVersions lists the published versions of a package. On the stable channel, versions newer than the latest
+// dist-tag are not listed: they were published under another tag, like "next".
+func (u Updater) listNPMVersions(ctx context.Context, name string, scheme VersionScheme, ch Channel, dates versionDates) ([]string, error) {
+	doc, err := u.fetchNPMPackument(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := doc.DistTags["latest"]
+	versions := make([]string, 0, len(doc.Versions))
+	for v := range doc.Versions {
+		if ch == ChannelStable && scheme.Valid(latest) && scheme.Valid(v) && scheme.Compare(v, latest) > 0 {
+			logrus.WithFields(logrus.Fields{"version": v, "latest": latest}).Debug("skipping version newer than the latest dist-tag")
+			continue
+		}
+		versions = append(versions, v)
+		dates.add(v, doc.Time[v])
+	}
+	return versions, nil
+}
+
+// updatedNPMHash downloads the tarball of the next version, and hashes it after verifying it against the registry's
+// published integrity.
+func (u Updater) updatedNPMHash(ctx context.Context, update updater.Update, oldHash string) (string, error) {
+	name, _ := u.npmPackage(expandVersion(update.Path, update.Previous))
+	doc, err := u.fetchNPMPackument(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	version, ok := doc.Versions[nextVersion(update)]
+	if !ok {
+		return "", fmt.Errorf("npm package %s has no version %s", name, nextVersion(update))
+	}
+	dist := version.Dist
+
+	h, ok := hasher(oldHash)
+	if !ok {
+		return "", nil
+	}
+	res, err := getAsset(ctx, u.client, dist.Tarball)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	sha512Hash, sha1Hash := sha512.New(), sha1.New()
+	if _, err := io.Copy(io.MultiWriter(h, sha512Hash, sha1Hash), res.Body); err != nil {
+		return "", err
+	}
+
+	switch {
+	case strings.HasPrefix(dist.Integrity, "sha512-"):
+		if integrity := "sha512-" + base64.StdEncoding.EncodeToString(sha512Hash.Sum(nil)); integrity != dist.Integrity {
+			return "", fmt.Errorf("tarball %s does not match the registry's integrity %s", dist.Tarball, dist.Integrity)
+		}
+	case dist.Shasum != "":
+		if shasum := fmt.Sprintf("%x", sha1Hash.Sum(nil)); shasum != dist.Shasum {
+			return "", fmt.Errorf("tarball %s does not match the registry's shasum %s", dist.Tarball, dist.Shasum)
+		}
+	default:
+		return "", fmt.Errorf("npm package %s publishes no digest for %s", name, nextVersion(update))
+	}
+
+	newHash := fmt.Sprintf("%x", h.Sum(nil))
+	logrus.WithFields(logrus.Fields{
+		"url":  dist.Tarball,
+		"hash": newHash,
+	}).Debug("downloaded tarball matching registry integrity")
+	return newHash, nil
+}
@@ -0,0 +1,71 @@
+package brew
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thepwagner/action-update/updater"
+)
+
+// fakeNPM serves the scoped package @foo/bar, with tarballs of the given contents by version.
+func fakeNPM(t *testing.T, tarballs map[string]string, integrity func(contents string) map[string]string) *fakeServer {
+	srv := newFakeServer(t, nil)
+	versions := map[string]interface{}{}
+	for v, contents := range tarballs {
+		tarball := fmt.Sprintf("/@foo/bar/-/bar-%s.tgz", v)
+		srv.file(tarball, contents)
+		dist := integrity(contents)
+		dist["tarball"] = srv.URL + tarball
+		versions[v] = map[string]interface{}{"dist": dist}
+	}
+	srv.json("/@foo/bar", map[string]interface{}{
+		"dist-tags": map[string]string{"latest": "1.1.0", "next": "2.0.0"},
+		"versions":  versions,
+	})
+	return srv
+}
+
+func npmIntegrity(contents string) map[string]string {
+	sum := sha512.Sum512([]byte(contents))
+	return map[string]string{"integrity": "sha512-" + base64.StdEncoding.EncodeToString(sum[:])}
+}
+
+func TestUpdater_NPM(t *testing.T) {
+	tarballs := map[string]string{"1.0.0": "bar 1.0.0", "1.1.0": "bar 1.1.0", "2.0.0": "bar 2.0.0"}
+	srv := fakeNPM(t, tarballs, npmIntegrity)
+
+	const formula = "class Bar < Formula\n  url \"%s/@foo/bar/-/bar-%s.tgz\"\n  sha256 \"%s\"\nend\n"
+	f := newFormulaFixture(t, fmt.Sprintf(formula, srv.URL, "1.0.0", sha256Hex("bar 1.0.0")), WithNPM(srv.URL))
+
+	// 2.0.0 is only tagged "next":
+	update := f.check()
+	require.NotNil(t, update)
+	assert.Equal(t, "1.1.0", update.Next)
+	assert.Equal(t, fmt.Sprintf(formula, srv.URL, "1.1.0", sha256Hex("bar 1.1.0")), f.apply(update))
+}
+
+func TestUpdater_Update_NPMIntegrity(t *testing.T) {
+	cases := map[string]func(contents string) map[string]string{
+		"integrity": func(string) map[string]string { return npmIntegrity("tampered") },
+		"shasum": func(string) map[string]string {
+			return map[string]string{"shasum": fmt.Sprintf("%x", sha1.Sum([]byte("tampered")))}
+		},
+		"none": func(string) map[string]string { return map[string]string{} },
+	}
+	for label, integrity := range cases {
+		t.Run(label, func(t *testing.T) {
+			srv := fakeNPM(t, map[string]string{"1.0.0": "bar 1.0.0", "1.1.0": "bar 1.1.0"}, integrity)
+			formula := fmt.Sprintf("class Bar < Formula\n  url \"%s/@foo/bar/-/bar-1.0.0.tgz\"\n  sha256 \"%s\"\nend\n", srv.URL, sha256Hex("bar 1.0.0"))
+			f := newFormulaFixture(t, formula, WithNPM(srv.URL))
+			err := f.ApplyUpdate(context.Background(), updater.Update{Path: srv.URL + "/@foo/bar/-/bar-1.0.0.tgz", Previous: "1.0.0", Next: "1.1.0"})
+			assert.Error(t, err)
+			assert.Equal(t, formula, f.read())
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package brew
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/thepwagner/action-update/updater"
+)
+
+// patchBlocks returns the `patch do ... end` blocks within a block, including those nested in platform blocks.
+func patchBlocks(scope *Block) []*Block {
+	var patches []*Block
+	for _, b := range scope.Blocks {
+		switch {
+		case b.Kind == "patch":
+			patches = append(patches, b)
+		case platformBlocks[b.Kind]:
+			patches = append(patches, patchBlocks(b)...)
+		}
+	}
+	return patches
+}
+
+// checkPatches rejects updates to dependencies with a patch that references the previous version. Patches are not
+// updated: an updated patch may not exist, or may not be needed at all, so these need a human to review.
+func checkPatches(dep *formulaDep, update updater.Update) error {
+	for _, patch := range patchBlocks(dep.scope) {
+		patchURL, ok := patch.First("url").Arg()
+		if ok && strings.Contains(patchURL.Value, update.Previous) {
+			return fmt.Errorf("patch %s references the previous version, update it manually", patchURL.Value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package brew
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/thepwagner/action-update/updater"
+)
+
+const defaultPyPIURL = "https://pypi.org"
+
+type pypiProject struct {
+	Releases map[string][]pypiFile `json:"releases"`
+}
+
+type pypiFile struct {
+	Filename    string            `json:"filename"`
+	URL         string            `json:"url"`
+	PackageType string            `json:"packagetype"`
+	Digests     map[string]string `json:"digests"`
+	UploadTime  time.Time         `json:"upload_time_iso_8601"`
+	Yanked      bool              `json:"yanked"`
+}
+
+// sdistExtensions are the archive formats of source distributions.
+var sdistExtensions = []string{".tar.gz", ".zip", ".tar.bz2", ".tgz"}
+
+// pypiPackage identifies the project of a source distribution downloaded from PyPI, like
+// https://files.pythonhosted.org/packages/ab/cd/ef.../requests-2.31.0.tar.gz
+func (u Updater) pypiPackage(rawURL, version string) (string, bool) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || !strings.HasPrefix(parsed.Path, "/packages/") {
+		return "", false
+	}
+	base, err := url.Parse(u.pypiURL)
+	if err != nil {
+		return "", false
+	}
+	if parsed.Host != "files.pythonhosted.org" && parsed.Host != base.Host {
+		return "", false
+	}
+
+	filename := path.Base(parsed.Path)
+	i := strings.LastIndex(filename, "-"+version)
+	if i <= 0 {
+		return "", false
+	}
+	for _, ext := range sdistExtensions {
+		if filename[i+1+len(version):] == ext {
+			return filename[:i], true
+		}
+	}
+	return "", false
+}
+
+// pypiSource discovers source distributions published to PyPI.
+type pypiSource struct{ u *Updater }
+
+func (s pypiSource) Match(dep updater.Dependency) bool {
+	_, ok := s.u.pypiPackage(expandVersion(dep.Path, dep.Version), dep.Version)
+	return ok
+}
+
+func (s pypiSource) ListVersions(ctx context.Context, q VersionQuery) ([]SourceVersion, error) {
+	name, _ := s.u.pypiPackage(expandVersion(q.Path, q.Version), q.Version)
+	dates := versionDates{}
+	versions, err := s.u.listPyPIVersions(ctx, name, dates)
+	if err != nil {
+		return nil, err
+	}
+	return sourceVersions(versions, dates), nil
+}
+
+// nextURL returns the url of the next version's file, which includes a hash of the file rather than just the version.
+func (s pypiSource) nextURL(ctx context.Context, update updater.Update) (string, error) {
+	f, err := s.u.updatedPyPIFile(ctx, update)
+	if err != nil {
+		return "", err
+	}
+	return f.URL, nil
+}
+
+func (s pypiSource) ResolveHash(ctx context.Context, update updater.Update, oldHash string) (string, error) {
+	return s.u.updatedPyPIHash(ctx, update, oldHash)
+}
+
+var pypiNameRe = regexp.MustCompile(`[-_.]+`)
+
+// fetchPyPIProject fetches a project's releases from the JSON API.
+func (u Updater) fetchPyPIProject(ctx context.Context, name string) (*pypiProject, error) {
+	// Names are normalized as in PEP 503:
+	name = strings.ToLower(pypiNameRe.ReplaceAllString(name, "-"))
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/pypi/%s/json", strings.TrimSuffix(u.pypiURL, "/"), url.PathEscape(name)), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := u.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching pypi project %s: unexpected status %d", name, res.StatusCode)
+	}
+
+	var project pypiProject
+	if err := json.NewDecoder(res.Body).Decode(&project); err != nil {
+		return nil, fmt.Errorf("decoding pypi project %s: %w", name, err)
+	}
+	return &project, nil
+}
+
+// listPyPIVersions lists the versions of a project that have files which haven't been yanked.
+func (u Updater) listPyPIVersions(ctx context.Context, name string, dates versionDates) ([]string, error) {
+	project, err := u.fetchPyPIProject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(project.Releases))
+	for version, files := range project.Releases {
+		var uploaded time.Time
+		for _, f := range files {
+			if !f.Yanked && (uploaded.IsZero() || f.UploadTime.Before(uploaded)) {
+				uploaded = f.UploadTime
+			}
+		}
+		if uploaded.IsZero() {
+			continue
+		}
+		versions = append(versions, version)
+		dates.add(version, uploaded)
+	}
+	logrus.WithFields(logrus.Fields{
+		"project":  name,
+		"versions": len(versions),
+	}).Debug("fetched pypi versions")
+	return versions, nil
+}
+
+// updatedPyPIFile finds the file of the next version corresponding to a source distribution. Source distributions
+// may change filename beyond the version, e.g. when the project name is normalized, so the only sdist of the next
+// version is used if there's no exact match.
+func (u Updater) updatedPyPIFile(ctx context.Context, update updater.Update) (*pypiFile, error) {
+	oldURL := expandVersion(update.Path, update.Previous)
+	name, ok := u.pypiPackage(oldURL, update.Previous)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a pypi source distribution", oldURL)
+	}
+	project, err := u.fetchPyPIProject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	next := nextVersion(update)
+	filename := strings.ReplaceAll(path.Base(oldURL), update.Previous, next)
+	var sdists []pypiFile
+	for _, f := range project.Releases[next] {
+		if f.Yanked {
+			continue
+		}
+		if f.Filename == filename {
+			return &f, nil
+		}
+		if f.PackageType == "sdist" {
+			sdists = append(sdists, f)
+		}
+	}
+	if len(sdists) == 1 {
+		return &sdists[0], nil
+	}
+	return nil, fmt.Errorf("source distribution %s not found in pypi project %s", filename, name)
+}
+
+// updatedPyPIHash returns the digest the API lists for the next version's file, without downloading it.
+func (u Updater) updatedPyPIHash(ctx context.Context, update updater.Update, oldHash string) (string, error) {
+	f, err := u.updatedPyPIFile(ctx, update)
+	if err != nil {
+		return "", err
+	}
+	switch len(oldHash) {
+	case 64:
+		return f.Digests["sha256"], nil
+	default:
+		return "", fmt.Errorf("pypi does not list a digest like %q", oldHash)
+	}
+}
@@ -0,0 +1,72 @@
+package brew
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thepwagner/action-update/updater"
+)
+
+func pypiDigest(c string) string {
+	return strings.Repeat(c, 64)
+}
+
+func fakePyPI(t *testing.T) *fakeServer {
+	srv := newFakeServer(t, nil)
+	sdist := func(filename, digest, uploaded string, yanked bool) map[string]interface{} {
+		return map[string]interface{}{
+			"filename":             filename,
+			"url":                  fmt.Sprintf("%s/packages/%s/%s", srv.URL, digest[:4], filename),
+			"packagetype":          "sdist",
+			"digests":              map[string]string{"sha256": digest},
+			"upload_time_iso_8601": uploaded,
+			"yanked":               yanked,
+		}
+	}
+	srv.json("/pypi/foo-bar/json", map[string]interface{}{
+		"releases": map[string]interface{}{
+			"1.0.0": []interface{}{sdist("foo-bar-1.0.0.tar.gz", pypiDigest("a"), "2020-01-01T00:00:00Z", false)},
+			// Newer sdists normalize the project name:
+			"1.1.0":   []interface{}{sdist("foo_bar-1.1.0.tar.gz", pypiDigest("b"), "2020-02-01T00:00:00Z", false)},
+			"1.2.0":   []interface{}{sdist("foo_bar-1.2.0.tar.gz", pypiDigest("c"), "2020-03-01T00:00:00Z", true)},
+			"2.0.0b1": []interface{}{sdist("foo_bar-2.0.0b1.tar.gz", pypiDigest("d"), "2020-04-01T00:00:00Z", false)},
+			"3.0.0":   []interface{}{},
+		},
+	})
+	return srv
+}
+
+func TestUpdater_PyPI(t *testing.T) {
+	srv := fakePyPI(t)
+	f := newFormulaFixture(t, fmt.Sprintf(`class Foo < Formula
+  url "https://example.com/foo-1.2.3.tar.gz"
+
+  resource "foo-bar" do
+    url "%s/packages/aaaa/foo-bar-1.0.0.tar.gz"
+    sha256 "%s"
+  end
+end
+`, srv.URL, pypiDigest("a")), WithPyPI(srv.URL))
+
+	update, err := f.Check(context.Background(), f.deps(2)[1], nil)
+	require.NoError(t, err)
+	require.NotNil(t, update)
+	assert.Equal(t, "1.1.0", update.Next)
+
+	// Only the API is served, nothing is downloaded:
+	file, err := f.updatedPyPIFile(context.Background(), *update)
+	require.NoError(t, err)
+	assert.Equal(t, "foo_bar-1.1.0.tar.gz", file.Filename)
+}
+
+func TestUpdater_Update_PyPI(t *testing.T) {
+	srv := fakePyPI(t)
+	const formula = "class Foo < Formula\n  url \"https://example.com/foo-1.2.3.tar.gz\"\n\n  resource \"foo-bar\" do\n    url \"%s/packages/%s\"\n    sha256 \"%s\"\n  end\nend\n"
+	f := newFormulaFixture(t, fmt.Sprintf(formula, srv.URL, "aaaa/foo-bar-1.0.0.tar.gz", pypiDigest("a")), WithPyPI(srv.URL))
+	updated := f.apply(&updater.Update{Path: srv.URL + "/packages/aaaa/foo-bar-1.0.0.tar.gz", Previous: "1.0.0", Next: "1.1.0"})
+	assert.Equal(t, fmt.Sprintf(formula, srv.URL, "bbbb/foo_bar-1.1.0.tar.gz", pypiDigest("b")), updated)
+}
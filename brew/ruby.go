@@ -0,0 +1,532 @@
+package brew
+
+import (
+	"fmt"
+	"strings"
+)
+
+// This is not a Ruby interpreter: it's a tokenizer that knows enough Ruby syntax (strings, comments, heredocs,
+// regular expressions and percent literals) to reliably find the statements of a formula.
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokLabel
+	tokSymbol
+	tokString
+	tokRegexp
+	tokNumber
+	tokPunct
+	tokNewline
+	tokComment
+)
+
+type token struct {
+	kind tokenKind
+	// text is the identifier, label or symbol name, punctuation or comment.
+	text string
+	// start and end are the byte offsets of the whole token.
+	start, end int
+	// valStart and valEnd are the byte offsets of literal contents, without delimiters.
+	valStart, valEnd int
+	// flags holds regular expression options (e.g. "i").
+	flags string
+	// space is true if the token was preceded by whitespace.
+	space bool
+	line  int
+}
+
+func (t token) literal() bool {
+	switch t.kind {
+	case tokString, tokSymbol, tokRegexp, tokNumber:
+		return true
+	default:
+		return false
+	}
+}
+
+func (t token) is(kind tokenKind, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+// rubyKeywords are identifiers after which an expression (rather than an operator) is expected.
+var rubyKeywords = map[string]bool{
+	"and": true, "case": true, "elsif": true, "if": true, "in": true, "not": true, "or": true,
+	"return": true, "then": true, "unless": true, "until": true, "when": true, "while": true,
+}
+
+var rubyOperators = []string{
+	"**=", "<=>", "===", "...", "||=", "&&=", "<<=", ">>=",
+	"==", "!=", ">=", "<=", "&&", "||", "<<", ">>", "=>", "->", "..", "+=", "-=", "*=", "/=", "=~", "!~", "::", "&.",
+}
+
+type heredoc struct {
+	token    int
+	id       string
+	indented bool
+}
+
+type lexer struct {
+	src      string
+	pos      int
+	line     int
+	space    bool
+	tokens   []token
+	heredocs []heredoc
+}
+
+func tokenize(src string) ([]token, error) {
+	l := &lexer{src: src, line: 1}
+	if err := l.run(); err != nil {
+		return nil, err
+	}
+	return l.tokens, nil
+}
+
+func (l *lexer) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", l.line, fmt.Sprintf(format, args...))
+}
+
+func (l *lexer) peek(offset int) byte {
+	if i := l.pos + offset; i < len(l.src) {
+		return l.src[i]
+	}
+	return 0
+}
+
+func (l *lexer) emit(t token) {
+	t.space = l.space
+	if t.line == 0 {
+		t.line = l.line
+	}
+	l.tokens = append(l.tokens, t)
+	l.space = false
+}
+
+func (l *lexer) run() error {
+	for l.pos < len(l.src) {
+		if l.pos == 0 || l.src[l.pos-1] == '\n' {
+			if done, err := l.lineStart(); err != nil {
+				return err
+			} else if done {
+				return nil
+			}
+		}
+
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.emit(token{kind: tokNewline, text: "\n", start: l.pos, end: l.pos + 1})
+			l.pos++
+			l.line++
+			if err := l.readHeredocs(); err != nil {
+				return err
+			}
+		case c == ' ' || c == '\t' || c == '\r':
+			l.space = true
+			l.pos++
+		case c == '\\' && l.peek(1) == '\n':
+			l.space = true
+			l.pos += 2
+			l.line++
+		case c == '#':
+			start := l.pos
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+			l.emit(token{kind: tokComment, text: l.src[start:l.pos], start: start, end: l.pos})
+		case c == '\'' || c == '"' || c == '`':
+			if err := l.quoted(tokString, c, c, c != '\''); err != nil {
+				return err
+			}
+		case c == ':':
+			if err := l.colon(); err != nil {
+				return err
+			}
+		case isIdentStart(c):
+			l.ident()
+		case c >= '0' && c <= '9':
+			l.number()
+		case c == '/' && l.regexpStart():
+			if err := l.quoted(tokRegexp, '/', '/', true); err != nil {
+				return err
+			}
+		case c == '%' && l.valueExpected() && l.percentLiteral():
+			if err := l.percent(); err != nil {
+				return err
+			}
+		case c == '<' && l.valueExpected() && l.heredocStart():
+		case c == '?' && l.valueExpected() && l.peek(1) > ' ' && !isIdentChar(l.peek(2)):
+			// Character literal, e.g. ?a
+			l.emit(token{kind: tokString, start: l.pos, end: l.pos + 2, valStart: l.pos + 1, valEnd: l.pos + 2})
+			l.pos += 2
+		default:
+			l.punct()
+		}
+	}
+
+	if len(l.heredocs) > 0 {
+		return l.errorf("unterminated heredoc %s", l.heredocs[0].id)
+	}
+	return nil
+}
+
+// lineStart handles syntax only valid at the start of a line: embedded documents and __END__.
+func (l *lexer) lineStart() (bool, error) {
+	rest := l.src[l.pos:]
+	switch {
+	case strings.HasPrefix(rest, "__END__") && (len(rest) == 7 || rest[7] == '\n' || rest[7] == '\r'):
+		// Everything that follows is DATA, typically an inline patch.
+		return true, nil
+	case strings.HasPrefix(rest, "=begin") && (len(rest) == 6 || isSpace(rest[6])):
+		startLine := l.line
+		for {
+			nl := strings.IndexByte(l.src[l.pos:], '\n')
+			if nl < 0 {
+				return false, fmt.Errorf("line %d: unterminated =begin", startLine)
+			}
+			l.pos += nl + 1
+			l.line++
+			if strings.HasPrefix(l.src[l.pos:], "=end") {
+				for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+					l.pos++
+				}
+				return false, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (l *lexer) colon() error {
+	start := l.pos
+	switch next := l.peek(1); {
+	case next == ':':
+		l.emit(token{kind: tokPunct, text: "::", start: start, end: start + 2})
+		l.pos += 2
+	case next == '"' || next == '\'':
+		l.pos++
+		if err := l.quoted(tokSymbol, next, next, next == '"'); err != nil {
+			return err
+		}
+		t := &l.tokens[len(l.tokens)-1]
+		t.start = start
+		t.text = l.src[t.valStart:t.valEnd]
+	case isIdentStart(next) && next != '$':
+		l.pos++
+		for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+			l.pos++
+		}
+		if c := l.peek(0); (c == '?' || c == '!' || c == '=') && l.peek(1) != '=' && l.peek(1) != '>' {
+			l.pos++
+		}
+		l.emit(token{kind: tokSymbol, text: l.src[start+1 : l.pos], start: start, end: l.pos, valStart: start + 1, valEnd: l.pos})
+	default:
+		l.emit(token{kind: tokPunct, text: ":", start: start, end: start + 1})
+		l.pos++
+	}
+	return nil
+}
+
+func (l *lexer) ident() {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+		l.pos++
+	}
+	if c := l.peek(0); (c == '?' || c == '!') && l.peek(1) != '=' {
+		l.pos++
+	}
+	text := l.src[start:l.pos]
+
+	// foo: is a hash label, foo:: is constant scoping.
+	if l.peek(0) == ':' && l.peek(1) != ':' && text[0] != '@' && text[0] != '$' {
+		l.pos++
+		l.emit(token{kind: tokLabel, text: text, start: start, end: l.pos})
+		return
+	}
+	l.emit(token{kind: tokIdent, text: text, start: start, end: l.pos})
+}
+
+func (l *lexer) number() {
+	start := l.pos
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if isIdentChar(c) || (c == '.' && l.peek(1) >= '0' && l.peek(1) <= '9') {
+			l.pos++
+			continue
+		}
+		break
+	}
+	l.emit(token{kind: tokNumber, text: l.src[start:l.pos], start: start, end: l.pos, valStart: start, valEnd: l.pos})
+}
+
+func (l *lexer) punct() {
+	rest := l.src[l.pos:]
+	for _, op := range rubyOperators {
+		if strings.HasPrefix(rest, op) {
+			l.emit(token{kind: tokPunct, text: op, start: l.pos, end: l.pos + len(op)})
+			l.pos += len(op)
+			return
+		}
+	}
+	l.emit(token{kind: tokPunct, text: rest[:1], start: l.pos, end: l.pos + 1})
+	l.pos++
+}
+
+// valueExpected guesses whether an ambiguous character (/, %, ?) starts a literal or is an operator.
+func (l *lexer) valueExpected() bool {
+	for i := len(l.tokens) - 1; i >= 0; i-- {
+		prev := l.tokens[i]
+		switch prev.kind {
+		case tokComment:
+			continue
+		case tokNewline, tokLabel:
+			return true
+		case tokPunct:
+			return prev.text != ")" && prev.text != "]" && prev.text != "}"
+		case tokIdent:
+			if rubyKeywords[prev.text] {
+				return true
+			}
+			// A command call argument: `regex /foo/` but not `a / b`
+			return l.space && !isSpace(l.peek(1))
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// regexpStart guesses whether a / starts a regular expression. After a method name, `regex /foo/` is a regular
+// expression but `a /2` is a division: the expression must be closed on the same line.
+func (l *lexer) regexpStart() bool {
+	if !l.valueExpected() {
+		return false
+	}
+	for i := len(l.tokens) - 1; i >= 0; i-- {
+		if prev := l.tokens[i]; prev.kind != tokComment {
+			if prev.kind != tokIdent || rubyKeywords[prev.text] {
+				return true
+			}
+			break
+		}
+	}
+	for i := l.pos + 1; i < len(l.src) && l.src[i] != '\n'; i++ {
+		switch l.src[i] {
+		case '\\':
+			i++
+		case '/':
+			return true
+		}
+	}
+	return false
+}
+
+func (l *lexer) percentLiteral() bool {
+	next := l.peek(1)
+	switch next {
+	case 'q', 'Q', 'w', 'W', 'i', 'I', 'r', 's', 'x':
+		next = l.peek(2)
+	}
+	return next != 0 && !isIdentChar(next) && !isSpace(next) && next != '='
+}
+
+func (l *lexer) percent() error {
+	start := l.pos
+	l.pos++
+	kind, interp := tokString, true
+	switch l.peek(0) {
+	case 'r':
+		kind = tokRegexp
+		l.pos++
+	case 's':
+		kind, interp = tokSymbol, false
+		l.pos++
+	case 'q', 'w', 'i':
+		interp = false
+		l.pos++
+	case 'Q', 'W', 'I', 'x':
+		l.pos++
+	}
+
+	open := l.peek(0)
+	closer := open
+	switch open {
+	case '(':
+		closer = ')'
+	case '[':
+		closer = ']'
+	case '{':
+		closer = '}'
+	case '<':
+		closer = '>'
+	}
+	if err := l.quoted(kind, open, closer, interp); err != nil {
+		return err
+	}
+	l.tokens[len(l.tokens)-1].start = start
+	return nil
+}
+
+// quoted scans a delimited literal starting at the opening delimiter, and emits it as a token.
+func (l *lexer) quoted(kind tokenKind, open, closer byte, interp bool) error {
+	start, startLine := l.pos, l.line
+	l.pos++
+	valStart := l.pos
+	depth := 0
+	for {
+		if l.pos >= len(l.src) {
+			return fmt.Errorf("line %d: unterminated literal %c", startLine, open)
+		}
+		c := l.src[l.pos]
+		switch {
+		case c == '\\':
+			if l.peek(1) == '\n' {
+				l.line++
+			}
+			l.pos += 2
+			continue
+		case c == '\n':
+			l.line++
+		case interp && c == '#' && l.peek(1) == '{':
+			if err := l.interpolation(); err != nil {
+				return err
+			}
+			continue
+		case c == closer && depth == 0:
+			valEnd := l.pos
+			l.pos++
+			t := token{kind: kind, start: start, end: l.pos, valStart: valStart, valEnd: valEnd, line: startLine}
+			if kind == tokRegexp {
+				for l.pos < len(l.src) && strings.IndexByte("imxounse", l.src[l.pos]) >= 0 {
+					l.pos++
+				}
+				t.flags = l.src[t.end:l.pos]
+				t.end = l.pos
+			}
+			l.emit(t)
+			return nil
+		case c == closer:
+			depth--
+		case c == open && open != closer:
+			depth++
+		}
+		l.pos++
+	}
+}
+
+// interpolation skips a #{...} expression within a string, which may itself contain strings.
+func (l *lexer) interpolation() error {
+	startLine := l.line
+	l.pos += 2
+	depth := 1
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				l.pos++
+				return nil
+			}
+		case '\n':
+			l.line++
+		case '\'', '"':
+			// Scan the nested string without emitting it:
+			n := len(l.tokens)
+			if err := l.quoted(tokString, c, c, c == '"'); err != nil {
+				return err
+			}
+			l.tokens = l.tokens[:n]
+			continue
+		}
+		l.pos++
+	}
+	return fmt.Errorf("line %d: unterminated interpolation", startLine)
+}
+
+// heredocStart recognizes <<~EOS, <<-EOS, <<EOS and quoted variants. The body is read after the current line.
+func (l *lexer) heredocStart() bool {
+	if l.peek(1) != '<' {
+		return false
+	}
+	i := l.pos + 2
+	indented := false
+	if i < len(l.src) && (l.src[i] == '~' || l.src[i] == '-') {
+		indented = true
+		i++
+	}
+	var quote byte
+	if i < len(l.src) && (l.src[i] == '\'' || l.src[i] == '"') {
+		quote = l.src[i]
+		i++
+	}
+	idStart := i
+	for i < len(l.src) && isIdentChar(l.src[i]) {
+		i++
+	}
+	if i == idStart {
+		return false
+	}
+	id := l.src[idStart:i]
+	if quote != 0 {
+		if i >= len(l.src) || l.src[i] != quote {
+			return false
+		}
+		i++
+	} else if !indented && strings.ToUpper(id) != id {
+		// `foo <<bar` is a shift; bare heredoc identifiers are conventionally upper case.
+		return false
+	}
+
+	l.heredocs = append(l.heredocs, heredoc{token: len(l.tokens), id: id, indented: indented})
+	l.emit(token{kind: tokString, start: l.pos, end: i})
+	l.pos = i
+	return true
+}
+
+func (l *lexer) readHeredocs() error {
+	for _, h := range l.heredocs {
+		bodyStart := l.pos
+		for {
+			if l.pos >= len(l.src) {
+				return l.errorf("unterminated heredoc %s", h.id)
+			}
+			lineEnd := strings.IndexByte(l.src[l.pos:], '\n')
+			if lineEnd < 0 {
+				lineEnd = len(l.src)
+			} else {
+				lineEnd += l.pos
+			}
+			line := strings.TrimRight(l.src[l.pos:lineEnd], "\r")
+			if h.indented {
+				line = strings.TrimLeft(line, " \t")
+			}
+			if line == h.id {
+				t := &l.tokens[h.token]
+				t.valStart, t.valEnd = bodyStart, l.pos
+				l.pos = lineEnd
+				break
+			}
+			l.pos = lineEnd + 1
+			l.line++
+		}
+	}
+	l.heredocs = nil
+	return nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '@' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c >= 0x80
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,125 @@
+package brew
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/thepwagner/action-update/updater"
+)
+
+const defaultRubyGemsURL = "https://rubygems.org"
+
+// rubyGemVersion is a version of a gem, for one platform. Yanked versions are not listed by the API.
+type rubyGemVersion struct {
+	Number    string    `json:"number"`
+	Platform  string    `json:"platform"`
+	SHA       string    `json:"sha"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// rubyGem identifies the gem and platform of a download url, like https://rubygems.org/downloads/foo-1.2.3.gem or
+// https://rubygems.org/downloads/foo-1.2.3-x86_64-linux.gem
+func (u Updater) rubyGem(rawURL, version string) (name, platform string, ok bool) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || !strings.HasPrefix(parsed.Path, "/downloads/") {
+		return "", "", false
+	}
+	base, err := url.Parse(u.rubygemsURL)
+	if err != nil {
+		return "", "", false
+	}
+	if parsed.Host != "rubygems.org" && parsed.Host != base.Host {
+		return "", "", false
+	}
+
+	filename := path.Base(parsed.Path)
+	i := strings.LastIndex(filename, "-"+version)
+	if i <= 0 || !strings.HasSuffix(filename, ".gem") {
+		return "", "", false
+	}
+	switch rest := strings.TrimSuffix(filename[i+1+len(version):], ".gem"); {
+	case rest == "":
+		return filename[:i], "ruby", true
+	case strings.HasPrefix(rest, "-"):
+		return filename[:i], rest[1:], true
+	default:
+		return "", "", false
+	}
+}
+
+// rubyGemsSource discovers gems published to RubyGems.
+type rubyGemsSource struct{ u *Updater }
+
+func (s rubyGemsSource) Match(dep updater.Dependency) bool {
+	_, _, ok := s.u.rubyGem(expandVersion(dep.Path, dep.Version), dep.Version)
+	return ok
+}
+
+func (s rubyGemsSource) ListVersions(ctx context.Context, q VersionQuery) ([]SourceVersion, error) {
+	name, platform, _ := s.u.rubyGem(expandVersion(q.Path, q.Version), q.Version)
+	gemVersions, err := s.u.fetchRubyGemVersions(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]SourceVersion, 0, len(gemVersions))
+	for _, v := range gemVersions {
+		if v.Platform != platform {
+			continue
+		}
+		versions = append(versions, SourceVersion{Version: v.Number, Published: v.CreatedAt})
+	}
+	logrus.WithFields(logrus.Fields{
+		"gem":      name,
+		"platform": platform,
+		"versions": len(versions),
+	}).Debug("fetched gem versions")
+	return versions, nil
+}
+
+// ResolveHash returns the sha the API lists for the next version's gem, without downloading it.
+func (s rubyGemsSource) ResolveHash(ctx context.Context, update updater.Update, oldHash string) (string, error) {
+	if len(oldHash) != 64 {
+		return "", fmt.Errorf("rubygems does not list a digest like %q", oldHash)
+	}
+	name, platform, _ := s.u.rubyGem(expandVersion(update.Path, update.Previous), update.Previous)
+	gemVersions, err := s.u.fetchRubyGemVersions(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	next := nextVersion(update)
+	for _, v := range gemVersions {
+		if v.Number == next && v.Platform == platform {
+			return v.SHA, nil
+		}
+	}
+	return "", fmt.Errorf("gem %s has no version %s for platform %s", name, next, platform)
+}
+
+func (u Updater) fetchRubyGemVersions(ctx context.Context, name string) ([]rubyGemVersion, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/versions/%s.json", strings.TrimSuffix(u.rubygemsURL, "/"), url.PathEscape(name)), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := u.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching gem %s: unexpected status %d", name, res.StatusCode)
+	}
+
+	var versions []rubyGemVersion
+	if err := json.NewDecoder(res.Body).Decode(&versions); err != nil {
+		return nil, fmt.Errorf("decoding gem %s: %w", name, err)
+	}
+	return versions, nil
+}
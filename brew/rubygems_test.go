@@ -0,0 +1,39 @@
+package brew
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdater_RubyGems(t *testing.T) {
+	srv := newFakeServer(t, nil)
+	srv.json("/api/v1/versions/foo-bar.json", []map[string]string{
+		{"number": "1.2.0", "platform": "x86_64-linux", "sha": strings.Repeat("d", 64), "created_at": "2023-03-01T00:00:00Z"},
+		{"number": "1.1.0", "platform": "ruby", "sha": strings.Repeat("b", 64), "created_at": "2023-02-01T00:00:00Z"},
+		{"number": "1.1.0", "platform": "x86_64-linux", "sha": strings.Repeat("c", 64), "created_at": "2023-02-01T00:00:00Z"},
+		{"number": "1.0.0", "platform": "ruby", "sha": strings.Repeat("a", 64), "created_at": "2023-01-01T00:00:00Z"},
+	})
+
+	cases := map[string]struct {
+		gem            string
+		version        string
+		previous, next string
+	}{
+		"ruby": {gem: "foo-bar-%s.gem", version: "1.0.0", previous: strings.Repeat("a", 64), next: strings.Repeat("b", 64)},
+		// 1.2.0 was only released for this platform:
+		"platform": {gem: "foo-bar-%s-x86_64-linux.gem", version: "1.1.0", previous: strings.Repeat("c", 64), next: strings.Repeat("d", 64)},
+	}
+	for label, tc := range cases {
+		t.Run(label, func(t *testing.T) {
+			formula := "class Foo < Formula\n  url \"%s/downloads/" + tc.gem + "\"\n  sha256 \"%s\"\nend\n"
+			f := newFormulaFixture(t, fmt.Sprintf(formula, srv.URL, tc.version, tc.previous), WithRubyGems(srv.URL))
+			update := f.check()
+			require.NotNil(t, update)
+			assert.Equal(t, fmt.Sprintf(formula, srv.URL, update.Next, tc.next), f.apply(update))
+		})
+	}
+}
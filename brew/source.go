@@ -0,0 +1,91 @@
+package brew
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/thepwagner/action-update/updater"
+)
+
+// Source discovers the versions of dependencies downloaded from an upstream, like a release API or package registry,
+// and the hashes of their updated artifacts.
+type Source interface {
+	// Match returns true if the source serves the dependency.
+	Match(dep updater.Dependency) bool
+	// ListVersions lists the available versions of a dependency, which may include older or unrelated versions.
+	ListVersions(ctx context.Context, q VersionQuery) ([]SourceVersion, error)
+	// ResolveHash returns the hash of an artifact's next version, using the same algorithm as its previous hash.
+	// An empty hash is returned if it can't be found.
+	ResolveHash(ctx context.Context, update updater.Update, oldHash string) (string, error)
+}
+
+// VersionQuery describes the dependency a source lists versions for.
+type VersionQuery struct {
+	updater.Dependency
+	// Scheme orders the dependency's versions.
+	Scheme VersionScheme
+	// Channel is the least stable kind of release wanted. Versions outside the channel are removed after listing.
+	Channel Channel
+
+	// dep is the dependency within its formula, if it was found.
+	dep *formulaDep
+}
+
+// formulaDep returns the dependency within its formula, or a dependency without directives if it wasn't found.
+func (q VersionQuery) formulaDep() *formulaDep {
+	if q.dep != nil {
+		return q.dep
+	}
+	return &formulaDep{Dependency: q.Dependency}
+}
+
+// SourceVersion is a version listed by a source.
+type SourceVersion struct {
+	Version string
+	// Published is when the version was released, or zero if unknown.
+	Published time.Time
+}
+
+func sourceVersions(versions []string, dates versionDates) []SourceVersion {
+	ret := make([]SourceVersion, 0, len(versions))
+	for _, v := range versions {
+		ret = append(ret, SourceVersion{Version: v, Published: dates[v]})
+	}
+	return ret
+}
+
+// releaseHashResolver is implemented by sources that resolve every hash of a dependency (e.g. one per platform) from
+// a single release.
+type releaseHashResolver interface {
+	resolveHashes(ctx context.Context, dep *formulaDep, update updater.Update, oldHashes []string) (map[string]string, error)
+}
+
+// urlResolver is implemented by sources whose artifact urls change beyond the version between releases.
+type urlResolver interface {
+	nextURL(ctx context.Context, update updater.Update) (string, error)
+}
+
+// source returns the first source to match a dependency: registered sources, then the built-in sources.
+func (u Updater) source(dep updater.Dependency) (Source, error) {
+	sources := append([]Source{}, u.sources...)
+	sources = append(sources,
+		githubSource{&u},
+		gitlabSource{&u},
+		golangSource{&u},
+		pypiSource{&u},
+		npmSource{&u},
+		cratesSource{&u},
+		rubyGemsSource{&u},
+		gnuSource{&u},
+		mavenSource{&u},
+		hashicorpSource{&u},
+		apacheSource{&u},
+	)
+	for _, s := range sources {
+		if s.Match(dep) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("no source can check %s", dep.Path)
+}
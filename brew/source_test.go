@@ -0,0 +1,62 @@
+package brew
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thepwagner/action-update/updater"
+)
+
+// artifactSource serves an internal artifact server, with hashes by version.
+type artifactSource struct {
+	prefix string
+	hashes map[string]string
+}
+
+func (s artifactSource) Match(dep updater.Dependency) bool {
+	return strings.HasPrefix(dep.Path, s.prefix)
+}
+
+func (s artifactSource) ListVersions(_ context.Context, _ VersionQuery) ([]SourceVersion, error) {
+	versions := make([]SourceVersion, 0, len(s.hashes))
+	for v := range s.hashes {
+		versions = append(versions, SourceVersion{Version: v})
+	}
+	return versions, nil
+}
+
+func (s artifactSource) ResolveHash(_ context.Context, update updater.Update, oldHash string) (string, error) {
+	if s.hashes[update.Previous] != oldHash {
+		return "", nil
+	}
+	return s.hashes[update.Next], nil
+}
+
+func TestUpdater_Source(t *testing.T) {
+	src := artifactSource{
+		// Registered sources take precedence over the built-in sources:
+		prefix: "https://github.com/internal/",
+		hashes: map[string]string{
+			"1.0.0": strings.Repeat("a", 64),
+			"1.1.0": strings.Repeat("b", 64),
+			"1.2.0": strings.Repeat("c", 64),
+		},
+	}
+
+	const formula = "class Foo < Formula\n  url \"https://github.com/internal/foo-%s.tar.gz\"\n  sha256 \"%s\"\nend\n"
+	f := newFormulaFixture(t, fmt.Sprintf(formula, "1.0.0", src.hashes["1.0.0"]), WithSource(src))
+	update := f.check()
+	require.NotNil(t, update)
+	assert.Equal(t, "1.2.0", update.Next)
+	assert.Equal(t, fmt.Sprintf(formula, "1.2.0", src.hashes["1.2.0"]), f.apply(update))
+}
+
+func TestUpdater_Check_NoSource(t *testing.T) {
+	u := NewUpdater(t.TempDir())
+	_, err := u.Check(context.Background(), updater.Dependency{Path: "https://example.com/foo.tar.gz", Version: "1.0.0"}, nil)
+	assert.EqualError(t, err, "no source can check https://example.com/foo.tar.gz")
+}
@@ -0,0 +1,150 @@
+package brew
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// tagPattern maps between versions and the tags of their releases, using a template like "cli/v#{version}" or
+// "rel_#{version.dots_to_underscores}".
+type tagPattern struct {
+	template string
+	re       *regexp.Regexp
+	// sep separates the components of the version within tags.
+	sep string
+}
+
+// tagSeparators are the version methods a tag template may use, and the separator they produce. Detection tries them
+// in order.
+var tagSeparators = []struct{ method, sep string }{
+	{method: "", sep: "."},
+	{method: ".dots_to_underscores", sep: "_"},
+	{method: ".dots_to_hyphens", sep: "-"},
+}
+
+// tagSeparator returns the separator produced by a version method.
+func tagSeparator(method string) (string, bool) {
+	for _, s := range tagSeparators {
+		if s.method == method {
+			return s.sep, true
+		}
+	}
+	return "", false
+}
+
+func parseTagPattern(template string) (*tagPattern, error) {
+	matches := interpolationRe.FindAllStringSubmatchIndex(template, -1)
+	if len(matches) != 1 {
+		return nil, fmt.Errorf("tag pattern %q must interpolate #{version} once", template)
+	}
+	m := matches[0]
+	name, method := template[m[2]:m[3]], template[m[4]:m[5]]
+	sep, ok := tagSeparator(method)
+	if !strings.EqualFold(name, "version") || !ok {
+		return nil, fmt.Errorf("tag pattern %q must interpolate #{version}, #{version.dots_to_underscores} or #{version.dots_to_hyphens}", template)
+	}
+	re := regexp.MustCompile("^" + regexp.QuoteMeta(template[:m[0]]) + `(\d.*?)` + regexp.QuoteMeta(template[m[1]:]) + "$")
+	return &tagPattern{template: template, re: re, sep: sep}, nil
+}
+
+// version extracts the version from a tag, returning false if the tag doesn't match the pattern.
+func (p *tagPattern) version(tag string) (string, bool) {
+	m := p.re.FindStringSubmatch(tag)
+	if m == nil {
+		return "", false
+	}
+	return strings.ReplaceAll(m[1], p.sep, "."), true
+}
+
+// tag returns the tag of a version.
+func (p *tagPattern) tag(version string) string {
+	return expandVersion(p.template, version)
+}
+
+// githubTagRe finds the tag in the path of release assets and source archives.
+var githubTagRe = regexp.MustCompile(`^/[^/]+/[^/]+/(?:releases/download/(.+)/[^/]+|archive/(?:refs/tags/)?(.+?)\.(?:tar\.gz|tgz|zip))$`)
+
+// gitlabTagRe finds the tag in the path of GitLab source archives and release downloads, on any host.
+var gitlabTagRe = regexp.MustCompile(`^/.+?/-/(?:archive/([^/]+)/[^/]+|releases/([^/]+)/downloads/.+)$`)
+
+// detectTagPattern derives a tag pattern from the GitHub or GitLab release the URL downloads, returning nil if the URL
+// isn't for a tagged release or the tag doesn't include the version.
+func detectTagPattern(rawURL, version string) *tagPattern {
+	parsed, err := url.Parse(expandVersion(rawURL, version))
+	if err != nil {
+		return nil
+	}
+	tagRe := gitlabTagRe
+	if parsed.Host == "github.com" {
+		tagRe = githubTagRe
+	}
+	m := tagRe.FindStringSubmatch(parsed.Path)
+	if m == nil {
+		return nil
+	}
+	tag := m[1] + m[2]
+
+	for _, s := range tagSeparators {
+		tagVersion := strings.ReplaceAll(version, ".", s.sep)
+		if i := strings.Index(tag, tagVersion); i >= 0 {
+			template := tag[:i] + "#{version" + s.method + "}" + tag[i+len(tagVersion):]
+			if p, err := parseTagPattern(template); err == nil {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// releaseTagPattern returns the tag pattern of a GitHub or GitLab dependency: from a tag directive, or detected from
+// its url.
+func (d *formulaDep) releaseTagPattern() *tagPattern {
+	if d.directives.tag != nil {
+		return d.directives.tag
+	}
+	return detectTagPattern(d.Path, d.Version)
+}
+
+// taggedRelease is a release on a forge like GitHub or GitLab, identified by its tag.
+type taggedRelease struct {
+	tag string
+	// published is zero if unknown, e.g. for tags without a release.
+	published time.Time
+}
+
+// releaseVersions converts the tags of a dependency's releases to versions, dropping unrelated tags, and records when
+// each was published. Without a tag-regex or tag pattern, tags are used as versions.
+func (d *formulaDep) releaseVersions(releases []taggedRelease, dates versionDates) []string {
+	versions := make([]string, 0, len(releases))
+	for _, release := range releases {
+		if version, ok := d.releaseVersion(release.tag); ok {
+			versions = append(versions, version)
+			dates.add(version, release.published)
+		}
+	}
+	return versions
+}
+
+// releaseVersion converts the tag of a GitHub dependency's release to a version, returning false for unrelated tags.
+func (d *formulaDep) releaseVersion(tag string) (string, bool) {
+	if d.directives.tagRegex != nil {
+		return d.directives.tagVersion(tag)
+	}
+	if p := d.releaseTagPattern(); p != nil {
+		return p.version(tag)
+	}
+	// Tags are used as versions, dropping the "v" prefix unless the current version has one:
+	if !strings.HasPrefix(d.Version, "v") {
+		return strings.TrimPrefix(tag, "v"), true
+	}
+	return tag, true
+}
+
+// currentRelease returns true if a tag is the release of the dependency's current version.
+func (d *formulaDep) currentRelease(tag string) bool {
+	version, ok := d.releaseVersion(tag)
+	return ok && strings.TrimPrefix(version, "v") == strings.TrimPrefix(d.Version, "v")
+}
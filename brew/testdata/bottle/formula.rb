@@ -0,0 +1,15 @@
+class Jq < Formula
+  desc "Lightweight and flexible command-line JSON processor"
+  homepage "https://stedolan.github.io/jq/"
+  url "https://github.com/stedolan/jq/releases/download/jq-1.6.0/jq-1.6.0.tar.gz"
+  sha256 "5de8c8e29aaa3fb9cc6b47bb27299f271354ebb72514e3accadc7d38b5bbaa72"
+
+  bottle do
+    rebuild 1
+    sha256 cellar: :any, arm64_monterey: "f70e1ae8df182b242ca004492cc0a664e2a8195e2e46f30546fe78e265d5eb87"
+    sha256 cellar: :any, big_sur:        "674b3ae41c399f1e8e44c271b0e6909babff9fcd2e04a2127d25e2407ea4dd33"
+    sha256 "6c6c7fd04ec4b4d4f3d4d8c5a1f1e6c8b4b2c5ff0a9b0fb76ae3ed5bb3c0a2d1" => :mojave
+  end
+
+  depends_on "oniguruma"
+end
@@ -0,0 +1,11 @@
+cask "firefox" do
+  version "84.0.2"
+  sha256 "f3e6ba4b1de8c4b2c3d29b3ba02a7c9f1a8b5c0b0d33b3b0f5e2c6a0d4c3c2a1"
+
+  url "https://download-installer.cdn.mozilla.net/pub/firefox/releases/#{version}/mac/en-US/Firefox%20#{version}.dmg",
+      verified: "download-installer.cdn.mozilla.net/pub/firefox/releases/"
+  name "Mozilla Firefox"
+  homepage "https://www.mozilla.org/firefox/"
+
+  app "Firefox.app"
+end
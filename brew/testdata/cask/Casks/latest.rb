@@ -0,0 +1,7 @@
+cask "latest" do
+  version :latest
+  sha256 :no_check
+
+  url "https://example.com/#{version}/Latest.dmg"
+  app "Latest.app"
+end
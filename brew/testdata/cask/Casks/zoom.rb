@@ -0,0 +1,10 @@
+cask "zoom" do
+  version "5.4.59780.1220,59780"
+  sha256 :no_check
+
+  url "https://cdn.zoom.us/prod/#{version.csv.first}/Zoom.pkg"
+  name "Zoom.us"
+  homepage "https://www.zoom.us/"
+
+  pkg "Zoom.pkg"
+end
@@ -0,0 +1,24 @@
+class Httpie < Formula
+  include Language::Python::Virtualenv
+
+  desc "User-friendly cURL replacement (command-line HTTP client)"
+  homepage "https://httpie.io/"
+  url "https://github.com/httpie/httpie/archive/2.3.0.tar.gz"
+  sha256 "2a0bd8d0b1a4ea2e4c0f1b6d3a2ee5d1c22a8bcf8ce8d6d6cc1b8f0cd0a2b6c3"
+
+  depends_on "python@3.9"
+
+  resource "Pygments" do
+    url "https://github.com/pygments/pygments/archive/2.7.4.tar.gz"
+    sha256 "4fb5e9e2cc4f8c9d7b7f6d3e0e4e9b1e8a7c2f7e3fd6a2e3a6e2d53b8f5e2a1d"
+  end
+
+  resource "requests" do
+    url "https://github.com/psf/requests/archive/v2.25.1.tar.gz"
+    sha256 "27973dd4a904a4f13b263a19c866c13b92a39ed1c964655f025f3f8d3d75b804"
+  end
+
+  def install
+    virtualenv_install_with_resources
+  end
+end
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/bmatcuk/doublestar/v3"
 	"github.com/google/go-github/v33/github"
@@ -15,10 +16,26 @@ import (
 )
 
 type Updater struct {
-	root       string
-	client     *http.Client
-	gpg        bool
-	pathFilter func(string) bool
+	root              string
+	client            *http.Client
+	gpg               bool
+	bottlePlaceholder string
+	pathFilter        func(string) bool
+	versionSchemes    []versionSchemeOverride
+	channel           Channel
+	releaseLine       ReleaseLine
+	minAge            time.Duration
+	gitlabURL         string
+	gitlabToken       string
+	pypiURL           string
+	npmURL            string
+	cratesURL         string
+	rubygemsURL       string
+	gnuMirrors        []gnuMirror
+	mavenURL          string
+	hashicorpURL      string
+	hashicorpKeyURL   string
+	sources           []Source
 
 	ghRepos *github.RepositoriesService
 }
@@ -27,9 +44,18 @@ func NewUpdater(root string, opts ...UpdaterOpt) *Updater {
 	client := http.DefaultClient
 	gh := github.NewClient(client)
 	u := &Updater{
-		root:    root,
-		client:  client,
-		ghRepos: gh.Repositories,
+		root:            root,
+		client:          client,
+		ghRepos:         gh.Repositories,
+		gitlabURL:       defaultGitLabURL,
+		pypiURL:         defaultPyPIURL,
+		npmURL:          defaultNPMURL,
+		cratesURL:       defaultCratesURL,
+		rubygemsURL:     defaultRubyGemsURL,
+		gnuMirrors:      append([]gnuMirror{}, defaultGNUMirrors...),
+		mavenURL:        defaultMavenURL,
+		hashicorpURL:    defaultHashiCorpURL,
+		hashicorpKeyURL: defaultHashiCorpKeyURL,
 	}
 	for _, o := range opts {
 		o(u)
@@ -45,18 +71,146 @@ func WithGPG(gpg bool) UpdaterOpt {
 	}
 }
 
+// WithBottlePlaceholder rewrites bottle hashes to a placeholder when a formula's version changes.
+// By default, stale bottle blocks are removed.
+func WithBottlePlaceholder(placeholder string) UpdaterOpt {
+	return func(u *Updater) {
+		u.bottlePlaceholder = placeholder
+	}
+}
+
+// WithVersionScheme orders the versions of formulae matching a path glob (relative to the root) using the given
+// scheme, rather than one detected from the current version.
+func WithVersionScheme(pattern string, scheme VersionScheme) UpdaterOpt {
+	return func(u *Updater) {
+		u.versionSchemes = append(u.versionSchemes, versionSchemeOverride{pattern: pattern, scheme: scheme})
+	}
+}
+
+// WithChannel allows updates to prereleases on the channel. By default, only stable releases are proposed.
+func WithChannel(ch Channel) UpdaterOpt {
+	return func(u *Updater) {
+		u.channel = ch
+	}
+}
+
+// WithReleaseLine selects which newer version is proposed relative to the current release line. By default, the
+// newest version overall is proposed.
+func WithReleaseLine(l ReleaseLine) UpdaterOpt {
+	return func(u *Updater) {
+		u.releaseLine = l
+	}
+}
+
+// WithMinAge holds back versions published more recently than the given age, or at an unknown date.
+func WithMinAge(age time.Duration) UpdaterOpt {
+	return func(u *Updater) {
+		u.minAge = age
+	}
+}
+
+// WithGitLab discovers releases from a GitLab instance other than gitlab.com, authenticating with a token if provided.
+func WithGitLab(baseURL, token string) UpdaterOpt {
+	return func(u *Updater) {
+		if baseURL != "" {
+			u.gitlabURL = baseURL
+		}
+		u.gitlabToken = token
+	}
+}
+
+// WithPyPI discovers Python packages from an index other than pypi.org, which must serve the PyPI JSON API.
+func WithPyPI(baseURL string) UpdaterOpt {
+	return func(u *Updater) {
+		if baseURL != "" {
+			u.pypiURL = baseURL
+		}
+	}
+}
+
+// WithNPM discovers Node packages from a registry other than registry.npmjs.org.
+func WithNPM(baseURL string) UpdaterOpt {
+	return func(u *Updater) {
+		if baseURL != "" {
+			u.npmURL = baseURL
+		}
+	}
+}
+
+// WithCrates discovers Rust crates from a registry other than crates.io, which must serve the crates.io API.
+func WithCrates(baseURL string) UpdaterOpt {
+	return func(u *Updater) {
+		if baseURL != "" {
+			u.cratesURL = baseURL
+		}
+	}
+}
+
+// WithRubyGems discovers gems from a server other than rubygems.org, which must serve the RubyGems API.
+func WithRubyGems(baseURL string) UpdaterOpt {
+	return func(u *Updater) {
+		if baseURL != "" {
+			u.rubygemsURL = baseURL
+		}
+	}
+}
+
+// WithGNUMirror discovers GNU releases from another mirror of ftp.gnu.org, which serves gnu-keyring.gpg at its root.
+func WithGNUMirror(baseURL string) UpdaterOpt {
+	return func(u *Updater) {
+		if baseURL == "" {
+			return
+		}
+		prefix := strings.TrimSuffix(baseURL, "/") + "/"
+		u.gnuMirrors = append(u.gnuMirrors, gnuMirror{prefix: prefix, keyring: prefix + "gnu-keyring.gpg"})
+	}
+}
+
+// WithMaven discovers Java artifacts from a Maven repository other than Maven Central, e.g. a repository manager.
+func WithMaven(baseURL string) UpdaterOpt {
+	return func(u *Updater) {
+		if baseURL != "" {
+			u.mavenURL = baseURL
+		}
+	}
+}
+
+// WithHashiCorp discovers releases from a mirror of releases.hashicorp.com, verifying them with the key at keyURL.
+func WithHashiCorp(baseURL, keyURL string) UpdaterOpt {
+	return func(u *Updater) {
+		if baseURL != "" {
+			u.hashicorpURL = baseURL
+		}
+		if keyURL != "" {
+			u.hashicorpKeyURL = keyURL
+		}
+	}
+}
+
+// WithSource registers a source of versions and hashes, e.g. for an internal artifact server. Registered sources are
+// consulted in order before the built-in sources.
+func WithSource(s Source) UpdaterOpt {
+	return func(u *Updater) {
+		u.sources = append(u.sources, s)
+	}
+}
+
+type versionSchemeOverride struct {
+	pattern string
+	scheme  VersionScheme
+}
+
 func (u Updater) Name() string {
 	return "brew"
 }
 
 func (u Updater) Dependencies(context.Context) ([]updater.Dependency, error) {
 	var deps []updater.Dependency
-	err := u.eachFormula(func(_, formula string) error {
-		formulaDeps, err := parseFormulaDeps(formula)
-		if err == nil {
-			deps = append(deps, formulaDeps...)
+	err := u.eachFormula(func(_ string, f *Formula) error {
+		for _, fd := range formulaDeps(f) {
+			deps = append(deps, fd.Dependency)
 		}
-		return err
+		return nil
 	})
 	if err != nil {
 		return nil, err
@@ -65,44 +219,489 @@ func (u Updater) Dependencies(context.Context) ([]updater.Dependency, error) {
 }
 
 func (u Updater) Check(ctx context.Context, dep updater.Dependency, filter func(string) bool) (*updater.Update, error) {
-	// FIXME: pass the filter function
-	switch {
-	case strings.HasPrefix(dep.Path, "https://github.com/"):
-		return checkGitHubRelease(ctx, u.ghRepos, dep)
-	case strings.HasPrefix(dep.Path, "https://golang.org/dl/go"):
-		return checkGolangRelease(ctx, u.client, dep)
-	default:
-		return checkApacheRelease(ctx, u.client, dep)
+	fd, err := u.findDep(dep)
+	if err != nil {
+		return nil, err
 	}
+	if fd != nil && fd.err != nil {
+		return nil, fd.err
+	}
+	if fd != nil && fd.directives.ignore {
+		logrus.WithField("path", dep.Path).Info("dependency ignored by update directive")
+		return nil, nil
+	}
+	scheme, err := u.versionScheme(fd, dep.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := u.versionChannel(fd, dep.Version)
+	dates := versionDates{}
+	candidates, err := u.listVersions(ctx, fd, dep, scheme, ch, dates)
+	if err != nil {
+		return nil, err
+	}
+	candidates = channelVersions(ch, candidates)
+	if fd != nil {
+		candidates = fd.directives.allowed(candidates)
+	}
+	line := u.releaseLine
+	if fd != nil && fd.directives.releaseLine != nil {
+		line = *fd.directives.releaseLine
+	}
+	next := line.nextVersion(scheme, dep.Version, candidates, filter)
+	for next != "" {
+		if !u.heldBack(ctx, dep, next, dates) {
+			break
+		}
+		candidates = withoutVersion(candidates, next)
+		next = line.nextVersion(scheme, dep.Version, candidates, filter)
+	}
+	if next == "" {
+		return nil, nil
+	}
+	return &updater.Update{
+		Path:     dep.Path,
+		Previous: dep.Version,
+		Next:     next,
+	}, nil
 }
 
-func (u Updater) ApplyUpdate(ctx context.Context, update updater.Update) error {
-	return u.eachFormula(func(path, formula string) error {
-		var oldnew []string
-		if semverIsh(update.Previous) != update.Previous && strings.HasPrefix(update.Next, "v") {
-			oldnew = append(oldnew, update.Previous, update.Next[1:])
+// listVersions lists the available versions of a dependency, which may include older or unrelated versions.
+// Publication dates are recorded where the source lists them.
+func (u Updater) listVersions(ctx context.Context, fd *formulaDep, dep updater.Dependency, scheme VersionScheme, ch Channel, dates versionDates) ([]string, error) {
+	if fd != nil {
+		switch kind, repo := splitSource(fd.directives.source); kind {
+		case "github":
+			split := strings.SplitN(repo, "/", 2)
+			releases, err := listGitHubReleases(ctx, u.ghRepos, split[0], split[1], ch, fd.currentRelease)
+			return fd.releaseVersions(releases, dates), err
+		case "gitlab":
+			releases, err := u.listGitLabReleases(ctx, gitlabRepo{baseURL: u.gitlabBaseURL(), path: repo}, ch, fd.currentRelease)
+			return fd.releaseVersions(releases, dates), err
+		}
+
+		lc, err := parseLivecheck(fd)
+		if err != nil {
+			return nil, err
+		}
+		if lc != nil && !lc.inferred() {
+			return u.livecheckVersions(ctx, fd, lc)
+		}
+		if fd.git() {
+			return listGitTagVersions(ctx, fd)
+		}
+	}
+
+	if strings.Contains(dep.Version, ",") {
+		return nil, fmt.Errorf("version %q includes a build number that can't be discovered from the url", dep.Version)
+	}
+	src, err := u.source(dep)
+	if err != nil {
+		return nil, err
+	}
+	listed, err := src.ListVersions(ctx, VersionQuery{Dependency: dep, Scheme: scheme, Channel: ch, dep: fd})
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]string, 0, len(listed))
+	for _, v := range listed {
+		versions = append(versions, v.Version)
+		dates.add(v.Version, v.Published)
+	}
+	return versions, nil
+}
+
+// versionChannel selects the channel a dependency is updated on: a channel directive, or the configured channel.
+// Dependencies already on a prerelease keep following prereleases of the same kind.
+func (u Updater) versionChannel(fd *formulaDep, version string) Channel {
+	ch := u.channel
+	if fd != nil && fd.directives.channel != nil {
+		ch = *fd.directives.channel
+	}
+	if current := versionChannel(version); current > ch {
+		ch = current
+	}
+	return ch
+}
+
+// channelVersions removes the versions outside a channel.
+func channelVersions(ch Channel, versions []string) []string {
+	ret := make([]string, 0, len(versions))
+	for _, v := range versions {
+		if ch.allows(v) {
+			ret = append(ret, v)
 		} else {
-			oldnew = append(oldnew, update.Previous, update.Next)
+			logrus.WithFields(logrus.Fields{"version": v, "channel": ch}).Debug("version excluded by channel")
+		}
+	}
+	return ret
+}
+
+// versionScheme selects the scheme for ordering a dependency's versions: a scheme directive, a configured override
+// for its formula, or one detected from the current version.
+func (u Updater) versionScheme(fd *formulaDep, version string) (VersionScheme, error) {
+	scheme := detectVersionScheme(csvFirst(version))
+	if fd != nil {
+		for _, o := range u.versionSchemes {
+			if m, _ := doublestar.Match(o.pattern, fd.file); m {
+				scheme = o.scheme
+				break
+			}
+		}
+		if fd.directives.scheme != nil {
+			scheme = fd.directives.scheme
+		}
+	}
+
+	if scheme == nil {
+		return nil, fmt.Errorf("no version scheme can order version %q", version)
+	}
+	if !scheme.Valid(csvFirst(version)) {
+		return nil, fmt.Errorf("version %q is not a valid %s version", version, scheme.Name())
+	}
+	if strings.Contains(version, ",") {
+		return csvScheme{scheme}, nil
+	}
+	return scheme, nil
+}
+
+// findDep locates a dependency within the formulae, returning nil if it can't be found.
+func (u Updater) findDep(dep updater.Dependency) (*formulaDep, error) {
+	var found *formulaDep
+	err := u.eachFormula(func(path string, f *Formula) error {
+		if found != nil {
+			return nil
+		}
+		for _, fd := range formulaDeps(f) {
+			if fd.Path == dep.Path && fd.Version == dep.Version {
+				found = fd
+				found.file, _ = filepath.Rel(u.root, path)
+				return nil
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
 
-		replaced := strings.NewReplacer(oldnew...).Replace(formula)
-		if replaced == formula {
+func (u Updater) ApplyUpdate(ctx context.Context, update updater.Update) error {
+	return u.eachFormula(func(path string, f *Formula) error {
+		var edits []edit
+		for _, dep := range formulaDeps(f) {
+			if dep.err != nil || !dep.matches(update) {
+				continue
+			}
+			if dep.directives.ignore || !dep.directives.pinned(update.Next) {
+				logrus.WithFields(logrus.Fields{
+					"path": path,
+					"next": update.Next,
+				}).Info("update skipped by update directive")
+				continue
+			}
+			depEdits, err := u.updateDep(ctx, f, dep, update)
+			if err != nil {
+				return err
+			}
+			edits = append(edits, depEdits...)
+		}
+		if len(edits) == 0 {
 			return nil
 		}
+		return ioutil.WriteFile(path, []byte(applyEdits(f.Source, edits)), 0600)
+	})
+}
+
+func (u Updater) updateDep(ctx context.Context, f *Formula, dep *formulaDep, update updater.Update) ([]edit, error) {
+	edits := versionEdits(f, dep, update)
+	if len(edits) == 0 {
+		return nil, nil
+	}
+	if !dep.resource() {
+		if err := checkPatches(dep, update); err != nil {
+			return nil, err
+		}
+	}
+
+	// Some urls can't be derived from the version, and are replaced entirely:
+	urlEdits, err := u.urlEdits(ctx, dep, update)
+	if err != nil {
+		return nil, err
+	}
+	if len(urlEdits) > 0 {
+		replaced := make([][2]int, 0, len(urlEdits))
+		for _, e := range urlEdits {
+			replaced = append(replaced, [2]int{e.Start, e.End})
+		}
+		var kept []edit
+		for _, e := range edits {
+			if !overlaps(replaced, e) {
+				kept = append(kept, e)
+			}
+		}
+		edits = append(kept, urlEdits...)
+	}
+
+	hashEdits, err := u.hashEdits(ctx, dep, update)
+	if err != nil {
+		return nil, err
+	}
+	edits = append(edits, hashEdits...)
+
+	revisionEdits, err := revisionEdits(ctx, dep, update)
+	if err != nil {
+		return nil, err
+	}
+	edits = append(edits, revisionEdits...)
+
+	if !dep.resource() {
+		edits = append(edits, bottleEdits(f, dep.scope, u.bottlePlaceholder)...)
+	}
+	return edits, nil
+}
+
+// hashEdits updates the checksum of each artifact. Dependencies with an artifact per platform are updated
+// atomically: every hash must be resolved, or none are changed.
+func (u Updater) hashEdits(ctx context.Context, dep *formulaDep, update updater.Update) ([]edit, error) {
+	var hashed []*artifact
+	for _, a := range dep.artifacts {
+		if len(a.hashes) == 1 {
+			hashed = append(hashed, a)
+		}
+	}
+	if len(hashed) == 0 {
+		return nil, nil
+	}
+
+	newHashes, err := u.updatedHashes(ctx, dep, update, hashed)
+	if err != nil {
+		return nil, fmt.Errorf("finding updated hash: %w", err)
+	}
+
+	edits := make([]edit, 0, len(hashed))
+	for _, a := range hashed {
+		oldHash := a.hashes[0]
+		newHash := newHashes[oldHash.Value]
+		if newHash == "" {
+			if len(hashed) > 1 {
+				return nil, fmt.Errorf("updated hash not found for %s artifact %s", a.platform, a.url.Value)
+			}
+			continue
+		}
+		if err := u.verifyMirrors(ctx, a, update, newHash); err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit{Start: oldHash.Start, End: oldHash.End, Text: newHash})
+	}
+	return edits, nil
+}
+
+// versionEdits replaces the previous version. Resources only update their urls, the formula updates everything but
+// its resources and bottles.
+func versionEdits(f *Formula, dep *formulaDep, update updater.Update) []edit {
+	next := nextVersion(update)
+
+	var ranges, excluded [][2]int
+	if dep.resource() {
+		for _, a := range dep.artifacts {
+			ranges = append(ranges, [2]int{a.url.Start, a.url.End})
+			for _, m := range a.mirrors {
+				ranges = append(ranges, [2]int{m.Start, m.End})
+			}
+		}
+		if dep.tag != nil {
+			ranges = append(ranges, [2]int{dep.tag.Start, dep.tag.End})
+		}
+	} else {
+		ranges = append(ranges, [2]int{0, len(f.Source)})
+		for _, b := range dep.scope.Blocks {
+			if b.Kind == "resource" || b.Kind == "bottle" {
+				excluded = append(excluded, [2]int{b.Start, b.End})
+			}
+		}
+		for _, b := range patchBlocks(dep.scope) {
+			excluded = append(excluded, [2]int{b.Start, b.End})
+		}
+	}
+	for _, a := range dep.artifacts {
+		for _, h := range a.hashes {
+			excluded = append(excluded, [2]int{h.Start, h.End})
+		}
+	}
+	if dep.revision != nil {
+		excluded = append(excluded, [2]int{dep.revision.Start, dep.revision.End})
+	}
+
+	var edits []edit
+	for _, r := range ranges {
+		edits = append(edits, replaceEdits(f.Source, r, update.Previous, next, excluded)...)
+	}
+
+	// Urls may separate the version differently, e.g. foo-1_2_3.tar.gz:
+	for _, sep := range versionSeparators {
+		prevVariant, nextVariant := strings.ReplaceAll(update.Previous, ".", sep), strings.ReplaceAll(next, ".", sep)
+		if prevVariant == update.Previous {
+			continue
+		}
+		for _, a := range dep.artifacts {
+			edits = append(edits, replaceEdits(f.Source, [2]int{a.url.Start, a.url.End}, prevVariant, nextVariant, excluded)...)
+			for _, m := range a.mirrors {
+				edits = append(edits, replaceEdits(f.Source, [2]int{m.Start, m.End}, prevVariant, nextVariant, excluded)...)
+			}
+		}
+	}
+	return edits
+}
+
+// replaceEdits replaces each occurrence of old within a range of the source, skipping excluded ranges.
+func replaceEdits(src string, r [2]int, old, new string, excluded [][2]int) []edit {
+	var edits []edit
+	for start, end := r[0], r[1]; start < end; {
+		i := strings.Index(src[start:end], old)
+		if i < 0 {
+			break
+		}
+		e := edit{Start: start + i, End: start + i + len(old), Text: new}
+		start = e.End
+		if !overlaps(excluded, e) {
+			edits = append(edits, e)
+		}
+	}
+	return edits
+}
 
-		if shasums := parseFormulaHashes(formula); len(shasums) == 1 {
-			oldHash := shasums[0]
-			newHash, err := u.updatedHash(ctx, update, oldHash)
+// nextVersion formats the next version like the previous, e.g. stripping the "v" from "v1.2.3" tags.
+func nextVersion(update updater.Update) string {
+	if semverIsh(update.Previous) != update.Previous && strings.HasPrefix(update.Next, "v") {
+		return update.Next[1:]
+	}
+	return update.Next
+}
+
+func overlaps(ranges [][2]int, e edit) bool {
+	for _, r := range ranges {
+		if e.Start < r[1] && r[0] < e.End {
+			return true
+		}
+	}
+	return false
+}
+
+// urlEdits replaces the urls and mirrors of artifacts whose source resolves their next url.
+func (u Updater) urlEdits(ctx context.Context, dep *formulaDep, update updater.Update) ([]edit, error) {
+	var edits []edit
+	for _, a := range dep.artifacts {
+		for _, lit := range append([]Literal{a.url}, a.mirrors...) {
+			nextURL, ok, err := u.resolvedURL(ctx, lit.Value, update)
 			if err != nil {
-				return fmt.Errorf("finding updated hash: %w", err)
+				return nil, err
 			}
-			if newHash != "" {
-				replaced = strings.ReplaceAll(replaced, oldHash, newHash)
+			if ok {
+				edits = append(edits, edit{Start: lit.Start, End: lit.End, Text: nextURL})
 			}
 		}
+	}
+	return edits, nil
+}
 
-		return ioutil.WriteFile(path, []byte(replaced), 0600)
-	})
+// resolvedURL returns the next url of an artifact, if its source resolves urls rather than only changing the version.
+func (u Updater) resolvedURL(ctx context.Context, rawURL string, update updater.Update) (string, bool, error) {
+	if interpolationRe.MatchString(rawURL) {
+		return "", false, nil
+	}
+	src, err := u.source(updater.Dependency{Path: rawURL, Version: update.Previous})
+	if err != nil {
+		return "", false, nil
+	}
+	r, ok := src.(urlResolver)
+	if !ok {
+		return "", false, nil
+	}
+	artifactUpdate := update
+	artifactUpdate.Path = rawURL
+	nextURL, err := r.nextURL(ctx, artifactUpdate)
+	if err != nil {
+		return "", false, err
+	}
+	return nextURL, true, nil
+}
+
+// updatedHashes resolves the updated hash of each artifact, keyed by the previous hash.
+func (u Updater) updatedHashes(ctx context.Context, dep *formulaDep, update updater.Update, artifacts []*artifact) (map[string]string, error) {
+	src, err := u.source(updater.Dependency{Path: update.Path, Version: update.Previous})
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := src.(releaseHashResolver); ok {
+		oldHashes := make([]string, 0, len(artifacts))
+		for _, a := range artifacts {
+			oldHashes = append(oldHashes, a.hashes[0].Value)
+		}
+		logrus.WithFields(logrus.Fields{
+			"hashes":   len(oldHashes),
+			"previous": update.Previous,
+			"next":     update.Next,
+		}).Debug("searching for updated release assets corresponding to hashes")
+		return r.resolveHashes(ctx, dep, update, oldHashes)
+	}
+
+	newHashes := make(map[string]string, len(artifacts))
+	for _, a := range artifacts {
+		artifactUpdate := update
+		artifactUpdate.Path = a.url.Value
+		oldHash := a.hashes[0].Value
+		newHash, err := u.updatedHash(ctx, artifactUpdate, oldHash)
+		if err != nil {
+			return nil, err
+		}
+		newHashes[oldHash] = newHash
+	}
+	return newHashes, nil
+}
+
+// listForgeReleases lists the releases of the GitHub or GitLab project a dependency downloads from.
+func (u Updater) listForgeReleases(ctx context.Context, dep *formulaDep, ch Channel) ([]taggedRelease, error) {
+	if project, ok := u.gitlabProject(dep.Path); ok {
+		return u.listGitLabReleases(ctx, project, ch, dep.currentRelease)
+	}
+	owner, name, err := parseGitHubRelease(dep.Path)
+	if err != nil {
+		return nil, err
+	}
+	return listGitHubReleases(ctx, u.ghRepos, owner, name, ch, dep.currentRelease)
+}
+
+// releaseUpdate resolves the tags of the GitHub or GitLab releases an update moves between, reversing the mapping from
+// tags to versions used by Check.
+func (u Updater) releaseUpdate(ctx context.Context, dep *formulaDep, update updater.Update) (releaseUpdate, error) {
+	ru := releaseUpdate{Update: update, previousTag: update.Previous, nextTag: update.Next}
+	if dep.directives.tagRegex != nil {
+		// Regular expressions can't be reversed, search for the tags instead:
+		releases, err := u.listForgeReleases(ctx, dep, ChannelBeta)
+		if err != nil {
+			return ru, err
+		}
+		ru.previousTag, ru.nextTag = "", ""
+		for _, release := range releases {
+			tag := release.tag
+			switch version, _ := dep.directives.tagVersion(tag); version {
+			case update.Previous:
+				ru.previousTag = tag
+			case nextVersion(update):
+				ru.nextTag = tag
+			}
+		}
+		if ru.previousTag == "" || ru.nextTag == "" {
+			return ru, fmt.Errorf("releases matching tag-regex for %s and %s not found for %s", update.Previous, update.Next, update.Path)
+		}
+	} else if p := dep.releaseTagPattern(); p != nil {
+		ru.previousTag = p.tag(update.Previous)
+		ru.nextTag = p.tag(nextVersion(update))
+	}
+	return ru, nil
 }
 
 func (u Updater) updatedHash(ctx context.Context, update updater.Update, oldHash string) (string, error) {
@@ -111,17 +710,16 @@ func (u Updater) updatedHash(ctx context.Context, update updater.Update, oldHash
 		"previous": update.Previous,
 		"next":     update.Next,
 	}).Debug("searching for updated artifact corresponding to hash")
-	switch {
-	case strings.HasPrefix(update.Path, "https://github.com/"):
-		return updatedGitHubHash(ctx, u.client, u.ghRepos, update, oldHash)
-	case strings.HasPrefix(update.Path, "https://golang.org/dl/go"):
-		return updatedGolangHash(ctx, u.client, update, oldHash)
-	default:
-		return updatedApacheHash(ctx, u.client, update, oldHash, u.gpg)
+	src, err := u.source(updater.Dependency{Path: update.Path, Version: update.Previous})
+	if err != nil {
+		return "", err
 	}
+	return src.ResolveHash(ctx, update, oldHash)
 }
 
-func (u *Updater) eachFormula(process func(path, formula string) error) error {
+// eachFormula parses and processes each formula. Formulae that can't be parsed are skipped, so they don't prevent
+// updating the rest of the tap.
+func (u *Updater) eachFormula(process func(path string, f *Formula) error) error {
 	formulae, err := doublestar.Glob(filepath.Join(u.root, "**", "*.rb"))
 	if err != nil {
 		return fmt.Errorf("globbing formulae: %w", err)
@@ -136,7 +734,12 @@ func (u *Updater) eachFormula(process func(path, formula string) error) error {
 		if err != nil {
 			return fmt.Errorf("reading formula %s: %w", f, err)
 		}
-		if err := process(f, string(formula)); err != nil {
+		parsed, err := parseFormula(string(formula))
+		if err != nil {
+			logrus.WithError(err).WithField("path", f).Warn("skipping formula that can't be parsed")
+			continue
+		}
+		if err := process(f, parsed); err != nil {
 			return fmt.Errorf("processing formula %s: %w", f, err)
 		}
 	}
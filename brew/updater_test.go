@@ -36,9 +36,21 @@ func TestUpdater_Dependencies(t *testing.T) {
 		"debian": {
 			{Path: "https://libvirt.org/sources/libvirt-1.0.2.tar.gz", Version: "1.0.2"},
 		},
+		"cask": {
+			{Path: "https://download-installer.cdn.mozilla.net/pub/firefox/releases/#{version}/mac/en-US/Firefox%20#{version}.dmg", Version: "84.0.2"},
+			{Path: "https://cdn.zoom.us/prod/#{version.csv.first}/Zoom.pkg", Version: "5.4.59780.1220,59780"},
+		},
+		"bottle": {
+			{Path: "https://github.com/stedolan/jq/releases/download/jq-1.6.0/jq-1.6.0.tar.gz", Version: "1.6.0"},
+		},
 		"hadoop":     {hadoop260},
 		"versionvar": {libvirt102},
 		"go":         {golang1156},
+		"resources": {
+			{Path: "https://github.com/httpie/httpie/archive/2.3.0.tar.gz", Version: "2.3.0"},
+			{Path: "https://github.com/pygments/pygments/archive/2.7.4.tar.gz", Version: "2.7.4"},
+			{Path: "https://github.com/psf/requests/archive/v2.25.1.tar.gz", Version: "2.25.1"},
+		},
 	})
 }
 
@@ -0,0 +1,230 @@
+package brew
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/mod/semver"
+)
+
+// VersionScheme parses and orders versions of a particular format.
+type VersionScheme interface {
+	// Name identifies the scheme in configuration.
+	Name() string
+	// Valid returns true if the version belongs to the scheme.
+	Valid(version string) bool
+	// Compare orders two valid versions, returning -1, 0 or 1.
+	Compare(a, b string) int
+	// Pattern is a regular expression matching versions of the scheme, for finding versions in text.
+	Pattern() string
+}
+
+type versionScheme struct {
+	name    string
+	pattern string
+	// detect matches versions this scheme should be selected for, which may be stricter than the versions it accepts.
+	detect  *regexp.Regexp
+	valid   *regexp.Regexp
+	compare func(a, b string) int
+}
+
+func newVersionScheme(name, pattern, detect string, compare func(a, b string) int) *versionScheme {
+	return &versionScheme{
+		name:    name,
+		pattern: pattern,
+		detect:  regexp.MustCompile(`^v?` + detect + `$`),
+		valid:   regexp.MustCompile(`^v?` + pattern + `$`),
+		compare: compare,
+	}
+}
+
+func (s *versionScheme) Name() string    { return s.name }
+func (s *versionScheme) Pattern() string { return s.pattern }
+
+func (s *versionScheme) Valid(version string) bool {
+	return s.valid.MatchString(version)
+}
+
+func (s *versionScheme) Compare(a, b string) int {
+	return s.compare(strings.TrimPrefix(a, "v"), strings.TrimPrefix(b, "v"))
+}
+
+var (
+	// SemverScheme orders semantic versions like 1.2.3 and 1.2.3-rc1. Shorthand like 1.2 is accepted, as golang.org/x/mod/semver does.
+	SemverScheme VersionScheme = newVersionScheme("semver",
+		`\d+\.\d+(?:\.\d+)?(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?`,
+		`\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?`,
+		func(a, b string) int { return semver.Compare(semverIsh(a), semverIsh(b)) })
+
+	// NumericScheme orders dotted numeric versions of any length like 3.12 and 1.2.3.4.
+	NumericScheme VersionScheme = newVersionScheme("numeric",
+		`\d+(?:\.\d+)*`,
+		`\d+(?:\.\d+)*`,
+		func(a, b string) int { return compareNumericParts(strings.Split(a, "."), strings.Split(b, ".")) })
+
+	// CalverScheme orders calendar versions like 2024.05.01 and 2024-05-01.
+	CalverScheme VersionScheme = newVersionScheme("calver",
+		`(?:19|20)\d{2}[.-]\d{1,2}(?:[.-]\d{1,2})?(?:[.-]\d+)?`,
+		`(?:19|20)\d{2}[.-]\d{1,2}(?:[.-]\d{1,2})?(?:[.-]\d+)?`,
+		func(a, b string) int { return compareNumericParts(calverSplit(a), calverSplit(b)) })
+
+	// LetterScheme orders versions with a letter suffix like openssl's 1.1.1w.
+	LetterScheme VersionScheme = newVersionScheme("letter",
+		`\d+(?:\.\d+)*[a-z]?`,
+		`\d+(?:\.\d+)*[a-z]`,
+		compareLetter)
+
+	// RevisionScheme orders revision numbers like r123.
+	RevisionScheme VersionScheme = newVersionScheme("revision",
+		`r\d+`,
+		`r\d+`,
+		func(a, b string) int { return compareNumeric(a[1:], b[1:]) })
+)
+
+// versionSchemes are tried in order when detecting the scheme of a version.
+var versionSchemes = []VersionScheme{RevisionScheme, CalverScheme, SemverScheme, LetterScheme, NumericScheme}
+
+// lookupVersionScheme returns the scheme with the given name.
+func lookupVersionScheme(name string) (VersionScheme, error) {
+	names := make([]string, 0, len(versionSchemes))
+	for _, s := range versionSchemes {
+		if s.Name() == name {
+			return s, nil
+		}
+		names = append(names, s.Name())
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown version scheme %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// detectVersionScheme selects a scheme from a current version, returning nil if none apply.
+func detectVersionScheme(version string) VersionScheme {
+	for _, s := range versionSchemes {
+		if s.(*versionScheme).detect.MatchString(version) {
+			return s
+		}
+	}
+	return nil
+}
+
+// csvScheme orders cask versions with a build number like "1.2.3,4567": the first component using the wrapped
+// scheme, then each remaining component numerically.
+type csvScheme struct {
+	VersionScheme
+}
+
+func (s csvScheme) Valid(version string) bool {
+	return s.VersionScheme.Valid(csvFirst(version))
+}
+
+func (s csvScheme) Compare(a, b string) int {
+	as, bs := strings.Split(a, ","), strings.Split(b, ",")
+	if c := s.VersionScheme.Compare(as[0], bs[0]); c != 0 {
+		return c
+	}
+	return compareNumericParts(as[1:], bs[1:])
+}
+
+// latestVersion returns the highest candidate newer than the current version that passes the filter, or "" if there
+// is none. The filter may be nil, and is given a semver form of each candidate (see filterVersion).
+func latestVersion(scheme VersionScheme, current string, candidates []string, filter func(string) bool) string {
+	latest := current
+	for _, c := range candidates {
+		if !scheme.Valid(c) || scheme.Compare(c, latest) <= 0 {
+			continue
+		}
+		if filter != nil && !filter(filterVersion(c)) {
+			logrus.WithField("version", c).Debug("version excluded by filter")
+			continue
+		}
+		latest = c
+	}
+	if latest == current {
+		return ""
+	}
+	return latest
+}
+
+// filterVersionRe matches the leading numeric components of a version.
+var filterVersionRe = regexp.MustCompile(`^v?(\d+)(?:[.-](\d+))?(?:[.-](\d+))?`)
+
+// filterVersion converts a version to the "v"-prefixed semver expected by filters like group ranges. Versions that
+// aren't semver are approximated by their first three numeric components, e.g. "1.1.1w" filters as "v1.1.1".
+func filterVersion(version string) string {
+	version = csvFirst(version)
+	if sv := semverIsh(version); sv != "" {
+		return sv
+	}
+	m := filterVersionRe.FindStringSubmatch(version)
+	if m == nil {
+		return version
+	}
+	parts := make([]string, 0, 3)
+	for _, p := range m[1:] {
+		parts = append(parts, trimZeros(p))
+	}
+	return "v" + strings.Join(parts, ".")
+}
+
+func semverIsh(s string) string {
+	if semver.IsValid(s) {
+		return s
+	}
+
+	if vt := fmt.Sprintf("v%s", s); semver.IsValid(vt) {
+		return vt
+	}
+	return ""
+}
+
+func calverSplit(v string) []string {
+	return strings.FieldsFunc(v, func(r rune) bool { return r == '.' || r == '-' })
+}
+
+// compareNumericParts compares versions part by part, treating missing parts as zero so 3.12 equals 3.12.0.
+func compareNumericParts(a, b []string) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		x, y := "0", "0"
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if c := compareNumeric(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareNumeric compares strings of digits of any length. Non-numeric strings are compared lexically.
+func compareNumeric(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(a) != len(b) && strings.Trim(a+b, "0123456789") == "" {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareLetter(a, b string) int {
+	aNum, aLetter := splitLetter(a)
+	bNum, bLetter := splitLetter(b)
+	if c := compareNumericParts(strings.Split(aNum, "."), strings.Split(bNum, ".")); c != 0 {
+		return c
+	}
+	return strings.Compare(aLetter, bLetter)
+}
+
+func splitLetter(v string) (string, string) {
+	if n := len(v); n > 0 && v[n-1] >= 'a' && v[n-1] <= 'z' {
+		return v[:n-1], v[n-1:]
+	}
+	return v, ""
+}
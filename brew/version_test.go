@@ -0,0 +1,105 @@
+package brew
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thepwagner/action-update/updater"
+)
+
+func TestDetectVersionScheme(t *testing.T) {
+	cases := map[string]VersionScheme{
+		"1.2.3":      SemverScheme,
+		"v1.2.3-rc1": SemverScheme,
+		"3.12":       NumericScheme,
+		"1.2.3.4":    NumericScheme,
+		"59780":      NumericScheme,
+		"2024.05.01": CalverScheme,
+		"2024-05-01": CalverScheme,
+		"1.1.1w":     LetterScheme,
+		"r123":       RevisionScheme,
+	}
+	for version, expected := range cases {
+		assert.Equal(t, expected.Name(), detectVersionScheme(version).Name(), version)
+	}
+	assert.Nil(t, detectVersionScheme("latest"))
+}
+
+func TestLatestVersion(t *testing.T) {
+	cases := []struct {
+		scheme     VersionScheme
+		current    string
+		candidates []string
+		expected   string
+	}{
+		{scheme: SemverScheme, current: "1.2.3", candidates: []string{"v1.2.4", "1.10.0", "2.0.0-rc1", "1.2.3.4"}, expected: "2.0.0-rc1"},
+		{scheme: SemverScheme, current: "1.15.8", candidates: []string{"1.16", "1.15.9"}, expected: "1.16"},
+		{scheme: NumericScheme, current: "3.12", candidates: []string{"3.9", "3.12.0", "3.13"}, expected: "3.13"},
+		{scheme: NumericScheme, current: "1.2.3.4", candidates: []string{"1.2.3.10", "1.2.3"}, expected: "1.2.3.10"},
+		{scheme: NumericScheme, current: "3.12", candidates: []string{"3.12.0"}, expected: ""},
+		{scheme: CalverScheme, current: "2024.05.01", candidates: []string{"2024.10.01", "2024.9.30", "1.0.0"}, expected: "2024.10.01"},
+		{scheme: LetterScheme, current: "1.1.1w", candidates: []string{"1.1.1v", "1.1.1x", "1.1.0z"}, expected: "1.1.1x"},
+		{scheme: LetterScheme, current: "1.1.1w", candidates: []string{"1.1.2"}, expected: "1.1.2"},
+		{scheme: RevisionScheme, current: "r99", candidates: []string{"r100", "r98", "100"}, expected: "r100"},
+		{scheme: csvScheme{NumericScheme}, current: "5.4.59780.1220,59780", candidates: []string{"5.4.59780.1220,59781", "5.4.59780.1219,59800"}, expected: "5.4.59780.1220,59781"},
+	}
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%s %s", tc.scheme.Name(), tc.current), func(t *testing.T) {
+			assert.Equal(t, tc.expected, latestVersion(tc.scheme, tc.current, tc.candidates, nil))
+		})
+	}
+}
+
+func TestLatestVersion_Filter(t *testing.T) {
+	group := &updater.Group{Range: ">= 1.0.0, < 2.0.0"}
+	cases := []struct {
+		scheme     VersionScheme
+		current    string
+		candidates []string
+		expected   string
+	}{
+		{scheme: SemverScheme, current: "1.2.3", candidates: []string{"2.0.0", "1.9.0", "1.10.0", "v1.11.0"}, expected: "v1.11.0"},
+		{scheme: SemverScheme, current: "1.2.3", candidates: []string{"2.0.0"}, expected: ""},
+		{scheme: NumericScheme, current: "1.2.3.4", candidates: []string{"2.0.0.1", "1.9.0.1"}, expected: "1.9.0.1"},
+		{scheme: LetterScheme, current: "1.1.1w", candidates: []string{"3.0.0", "1.1.1x"}, expected: "1.1.1x"},
+		{scheme: CalverScheme, current: "2024.05.01", candidates: []string{"2024.06.01"}, expected: ""},
+	}
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%s %s", tc.scheme.Name(), tc.current), func(t *testing.T) {
+			assert.Equal(t, tc.expected, latestVersion(tc.scheme, tc.current, tc.candidates, group.InRange))
+		})
+	}
+}
+
+func TestUpdater_Check_VersionScheme(t *testing.T) {
+	srv := newFakeServer(t, map[string]string{"/foo": `<a href="foo-1.2.3.tar.gz">foo-1.2.3.tar.gz</a>
+<a href="foo-1.2.3.10.tar.gz">foo-1.2.3.10.tar.gz</a>
+<a href="foo-1.2.3.4.tar.gz">foo-1.2.3.4.tar.gz</a>`})
+
+	cases := []struct {
+		version string
+		opts    []UpdaterOpt
+		next    string
+	}{
+		{version: "1.2.3.4", next: "1.2.3.10"},
+		{version: "1.2.3"},
+		{version: "1.2.3", opts: []UpdaterOpt{WithVersionScheme("**/foo.rb", NumericScheme)}, next: "1.2.3.10"},
+		{version: "1.2.3", opts: []UpdaterOpt{WithVersionScheme("bar.rb", NumericScheme)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.version, func(t *testing.T) {
+			f := newFormulaFixture(t, fmt.Sprintf("class Foo < Formula\n  url \"%s/foo/foo-%s.tar.gz\"\nend\n", srv.URL, tc.version), tc.opts...)
+			assert.Equal(t, tc.version, f.deps(1)[0].Version)
+
+			update := f.check()
+			if tc.next == "" {
+				assert.Nil(t, update)
+				return
+			}
+			require.NotNil(t, update)
+			assert.Equal(t, tc.next, update.Next)
+		})
+	}
+}
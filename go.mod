@@ -10,4 +10,5 @@ require (
 	github.com/stretchr/testify v1.7.0
 	github.com/thepwagner/action-update v0.0.38
 	golang.org/x/mod v0.4.1
+	golang.org/x/net v0.0.0-20200301022130-244492dfa37a
 )